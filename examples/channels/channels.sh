@@ -2,6 +2,7 @@
 # successfully passed from one goroutine to another via
 # our channel.
 $ go run channels.go 
+Sending message...
 ping
 
 # By default sends and receives block until both the
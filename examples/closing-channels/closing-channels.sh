@@ -1,11 +1,11 @@
 $ go run closing-channels.go 
-sent job 1
-received job 1
-sent job 2
-received job 2
-sent job 3
-received job 3
-sent all jobs
+sent job 1 # RE: (sent|received) job \d|sent all jobs
+received job 1 # RE: (sent|received) job \d|sent all jobs
+sent job 2 # RE: (sent|received) job \d|sent all jobs
+received job 2 # RE: (sent|received) job \d|sent all jobs
+sent job 3 # RE: (sent|received) job \d|sent all jobs
+received job 3 # RE: (sent|received) job \d|sent all jobs
+sent all jobs # RE: (sent|received) job \d|sent all jobs
 received all jobs
 received more jobs: false
 
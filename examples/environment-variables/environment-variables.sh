@@ -7,9 +7,9 @@ BAR:
 
 # The list of keys in the environment will depend on your
 # particular machine.
-TERM_PROGRAM
-PATH
-SHELL
+TERM_PROGRAM # RE: \w+
+PATH # RE: \w+
+SHELL # RE: \w+
 ...
 FOO
 
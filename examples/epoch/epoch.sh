@@ -1,10 +1,10 @@
 $ go run epoch.go 
-2012-10-31 16:13:58.292387 +0000 UTC
-1351700038
-1351700038292
-1351700038292387000
-2012-10-31 16:13:58 +0000 UTC
-2012-10-31 16:13:58.292387 +0000 UTC
+2012-10-31 16:13:58.292387 +0000 UTC # RE: \d{4}-\d\d-\d\d \d\d:\d\d:\d\d(\.\d+)? [-+]\d{4} \w+( m=[-+][\d.]+)?
+1351700038 # RE: \d+
+1351700038292 # RE: \d+
+1351700038292387000 # RE: \d+
+2012-10-31 16:13:58 +0000 UTC # RE: \d{4}-\d\d-\d\d \d\d:\d\d:\d\d(\.\d+)? [-+]\d{4} \w+( m=[-+][\d.]+)?
+2012-10-31 16:13:58.292387 +0000 UTC # RE: \d{4}-\d\d-\d\d \d\d:\d\d:\d\d(\.\d+)? [-+]\d{4} \w+( m=[-+][\d.]+)?
 
 # Next we'll look at another time-related task: time
 # parsing and formatting.
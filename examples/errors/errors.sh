@@ -6,3 +6,5 @@ Tea is ready!
 We should buy new tea!
 Tea is ready!
 Now it is dark.
+We couldn't make tea since we had no power.
+making tea: can't boil water
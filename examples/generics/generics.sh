@@ -1,3 +1,35 @@
 $ go run generics.go
+=== BASIC GENERICS ===
 index of zoo: 2
 list: [10 13 23]
+
+=== GENERICS + REFLECTION ===
+
+--- Old Way (Type-Specific Functions) ---
+People sorted by name: [{Alice 30} {Bob 25} {Charlie 35}]
+Products sorted by price: [...]
+
+--- New Way (Generics + Reflection) ---
+People sorted by Name (generic): [...]
+People sorted by Age descending (generic): [...]
+Products sorted by Name descending (generic): [...]
+
+--- Production Pattern (Higher-Order Functions) ---
+People sorted with comparator: [...]
+Products sorted with comparator: [...]
+
+=== KEY INSIGHTS ===
+1. Generics alone can't access struct fields (no ...
+2. Reflection enables runtime field access by name
+3. Generics + Reflection = Type-safe container + ...
+4. Higher-order functions (Comparator pattern) = ...
+
+In aac-backend:
+- This pattern eliminates 200+ duplicate sorting functions
+- Single implementation handles all models (Client, ...
+- Reflection cost is negligible for API response sorting
+Books sorted by Title (generic): [...]
+Books sorted with comparator by Title: [...]
+Products sorted by Price descending (generic): [...]
+People sorted with comparator by Age: [...]
+Books sorted with comparator by Title: [...]
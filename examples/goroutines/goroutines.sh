@@ -8,7 +8,6 @@ direct : 0
 direct : 1
 direct : 2
 goroutine : 0
-going
 goroutine : 1
 goroutine : 2
 done
@@ -7,7 +7,7 @@ hello world
 # binaries. We can do this using `go build`.
 $ go build hello-world.go
 $ ls
-hello-world	hello-world.go
+hello-world	hello-world.go # RE: hello-world\s+hello-world\.go
 
 # We can then execute the built binary directly.
 $ ./hello-world
@@ -11,7 +11,7 @@ panic: a problem
 
 goroutine 1 [running]:
 main.main()
-	/.../panic.go:12 +0x47
+	/.../panic.go:15 +0x...
 ...
 exit status 2
 
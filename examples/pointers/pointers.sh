@@ -5,4 +5,4 @@ $ go run pointers.go
 initial: 1
 zeroval: 1
 zeroptr: 0
-pointer: 0x42131100
+pointer: 0x42131100 # RE: pointer: 0x[0-9a-f]+
@@ -1,9 +1,9 @@
 # Some of the generated numbers may be
 # different when you run the sample.
 $ go run random-numbers.go
-68,56
-0.8090228139659177
-5.840125017402497,6.937056298890035
+68,56 # RE: \d+,\d+
+0.8090228139659177 # RE: 0\.\d+
+5.840125017402497,6.937056298890035 # RE: [5-9]\.\d+,[5-9]\.\d+
 94,49
 94,49
 
@@ -1,9 +1,9 @@
 $ go run range-over-built-in-types.go
 sum: 9
 index: 1
-a -> apple
-b -> banana
-key: a
-key: b
+a -> apple # RE: a -> apple|b -> banana
+b -> banana # RE: a -> apple|b -> banana
+key: a # RE: key: [ab]
+key: b # RE: key: [ab]
 0 103
 1 111
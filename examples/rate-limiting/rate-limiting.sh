@@ -1,17 +1,16 @@
 # Running our program we see the first batch of requests
 # handled once every ~200 milliseconds as desired.
 $ go run rate-limiting.go
-request 1 2012-10-19 00:38:18.687438 +0000 UTC
-request 2 2012-10-19 00:38:18.887471 +0000 UTC
-request 3 2012-10-19 00:38:19.087238 +0000 UTC
-request 4 2012-10-19 00:38:19.287338 +0000 UTC
-request 5 2012-10-19 00:38:19.487331 +0000 UTC
-
+request 1 2012-10-19 00:38:18.687438 +0000 UTC # RE: request 1 \d{4}-\d\d-\d\d \d\d:\d\d:\d\d(\.\d+)? [-+]\d{4} \w+( m=[-+][\d.]+)?
+request 2 2012-10-19 00:38:18.887471 +0000 UTC # RE: request 2 \d{4}-\d\d-\d\d \d\d:\d\d:\d\d(\.\d+)? [-+]\d{4} \w+( m=[-+][\d.]+)?
+request 3 2012-10-19 00:38:19.087238 +0000 UTC # RE: request 3 \d{4}-\d\d-\d\d \d\d:\d\d:\d\d(\.\d+)? [-+]\d{4} \w+( m=[-+][\d.]+)?
+request 4 2012-10-19 00:38:19.287338 +0000 UTC # RE: request 4 \d{4}-\d\d-\d\d \d\d:\d\d:\d\d(\.\d+)? [-+]\d{4} \w+( m=[-+][\d.]+)?
+request 5 2012-10-19 00:38:19.487331 +0000 UTC # RE: request 5 \d{4}-\d\d-\d\d \d\d:\d\d:\d\d(\.\d+)? [-+]\d{4} \w+( m=[-+][\d.]+)?
 # For the second batch of requests we serve the first
 # 3 immediately because of the burstable rate limiting,
 # then serve the remaining 2 with ~200ms delays each.
-request 1 2012-10-19 00:38:20.487578 +0000 UTC
-request 2 2012-10-19 00:38:20.487645 +0000 UTC
-request 3 2012-10-19 00:38:20.487676 +0000 UTC
-request 4 2012-10-19 00:38:20.687483 +0000 UTC
-request 5 2012-10-19 00:38:20.887542 +0000 UTC
+request 1 2012-10-19 00:38:20.487578 +0000 UTC # RE: request 1 \d{4}-\d\d-\d\d \d\d:\d\d:\d\d(\.\d+)? [-+]\d{4} \w+( m=[-+][\d.]+)?
+request 2 2012-10-19 00:38:20.487645 +0000 UTC # RE: request 2 \d{4}-\d\d-\d\d \d\d:\d\d:\d\d(\.\d+)? [-+]\d{4} \w+( m=[-+][\d.]+)?
+request 3 2012-10-19 00:38:20.487676 +0000 UTC # RE: request 3 \d{4}-\d\d-\d\d \d\d:\d\d:\d\d(\.\d+)? [-+]\d{4} \w+( m=[-+][\d.]+)?
+request 4 2012-10-19 00:38:20.687483 +0000 UTC # RE: request 4 \d{4}-\d\d-\d\d \d\d:\d\d:\d\d(\.\d+)? [-+]\d{4} \w+( m=[-+][\d.]+)?
+request 5 2012-10-19 00:38:20.887542 +0000 UTC # RE: request 5 \d{4}-\d\d-\d\d \d\d:\d\d:\d\d(\.\d+)? [-+]\d{4} \w+( m=[-+][\d.]+)?
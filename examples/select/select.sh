@@ -7,4 +7,6 @@ received two
 # Note that the total execution time is only ~2 seconds
 # since both the 1 and 2 second `Sleeps` execute
 # concurrently.
-real	0m2.245s
+real	0m2.245s # RE: real\s+0m2\.\d+s
+user	0m0.101s # RE: user\s+\d+m[\d.]+s
+sys	0m0.077s # RE: sys\s+\d+m[\d.]+s
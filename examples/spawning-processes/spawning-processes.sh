@@ -2,15 +2,16 @@
 # as if we had run them directly from the command-line.
 $ go run spawning-processes.go 
 > date
-Thu 05 May 2022 10:10:12 PM PDT
+Thu 05 May 2022 10:10:12 PM PDT # RE: \w{3} .*\d\d:\d\d:\d\d.*
 
 # date doesn't have a `-x` flag so it will exit with
 # an error message and non-zero return code.
-command exited with rc = 1
+command exit rc = 1
 > grep hello
 hello grep
 
 > ls -a -l -h
-drwxr-xr-x  4 mark 136B Oct 3 16:29 .
-drwxr-xr-x 91 mark 3.0K Oct 3 12:50 ..
--rw-r--r--  1 mark 1.3K Oct 3 16:28 spawning-processes.go
+total 12K # RE: total \S+
+drwxr-xr-x  4 mark 136B Oct 3 16:29 . # RE: d\S+ .* \.
+drwxr-xr-x 91 mark 3.0K Oct 3 12:50 .. # RE: d\S+ .* \.\.
+-rw-r--r--  1 mark 1.3K Oct 3 16:28 spawning-processes.go # RE: -\S+ .* spawning-processes\.go
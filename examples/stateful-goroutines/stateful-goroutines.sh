@@ -2,8 +2,8 @@
 # state management example completes about 80,000
 # total operations.
 $ go run stateful-goroutines.go
-readOps: 71708
-writeOps: 7177
+readOps: 71708 # RE: readOps: \d+
+writeOps: 7177 # RE: writeOps: \d+
 
 # For this particular case the goroutine-based approach
 # was a bit more involved than the mutex-based one. It
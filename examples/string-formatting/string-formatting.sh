@@ -14,7 +14,7 @@ float3: 1.234000E+08
 str1: "string"
 str2: "\"string\""
 str3: 6865782074686973
-pointer: 0xc0000ba000
+pointer: 0xc0000ba000 # RE: pointer: 0x[0-9a-f]+
 width1: |    12|   345|
 width2: |  1.20|  3.45|
 width3: |1.20  |3.45  |
@@ -7,4 +7,6 @@ $ go run structs.go
 Sean
 50
 51
+ModifyByValue 51
+ModifyByPointer 40
 {Rex true}
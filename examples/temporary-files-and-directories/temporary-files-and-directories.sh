@@ -1,3 +1,3 @@
 $ go run temporary-files-and-directories.go
-Temp file name: /tmp/sample610887201
-Temp dir name: /tmp/sampledir898854668
+Temp file name: /tmp/sample610887201 # RE: Temp file name: /tmp/sample\d+
+Temp dir name: /tmp/sampledir898854668 # RE: Temp dir name: /tmp/sampledir\d+
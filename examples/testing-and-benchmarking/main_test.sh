@@ -1,6 +1,6 @@
 # Run all tests in the current project in verbose mode.
 $ go test -v
-== RUN   TestIntMinBasic
+=== RUN   TestIntMinBasic
 --- PASS: TestIntMinBasic (0.00s)
 === RUN   TestIntMinTableDriven
 === RUN   TestIntMinTableDriven/0,1
@@ -15,15 +15,16 @@ $ go test -v
     --- PASS: TestIntMinTableDriven/0,-1 (0.00s)
     --- PASS: TestIntMinTableDriven/-1,0 (0.00s)
 PASS
-ok  	examples/testing-and-benchmarking	0.023s
+ok  	examples/testing-and-benchmarking	0.023s # RE: ok\s+\S*examples/testing-and-benchmarking\s+[\d.]+s
 
 # Run all benchmarks in the current project. All tests
 # are run prior to benchmarks. The `bench` flag filters
 # benchmark function names with a regexp.
 $ go test -bench=.
-goos: darwin
-goarch: arm64
-pkg: examples/testing
-BenchmarkIntMin-8 1000000000 0.3136 ns/op
+goos: darwin # RE: goos: \w+
+goarch: arm64 # RE: goarch: \w+
+pkg: examples/testing-and-benchmarking # RE: pkg: \S*examples/testing-and-benchmarking
+cpu: Apple M1 # RE: cpu: .*
+BenchmarkIntMin-8 1000000000 0.3136 ns/op # RE: BenchmarkIntMin(-\d+)?\s+\d+\s+[\d.]+ ns/op
 PASS
-ok  	examples/testing-and-benchmarking	0.351s
+ok  	examples/testing-and-benchmarking	0.351s # RE: ok\s+\S*examples/testing-and-benchmarking\s+[\d.]+s
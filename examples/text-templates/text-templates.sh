@@ -1,4 +1,4 @@
-$ go run templates.go 
+$ go run text-templates.go 
 Value: some text
 Value: 5
 Value: [Go Rust C++ C#]
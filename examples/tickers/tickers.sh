@@ -1,7 +1,7 @@
 # When we run this program the ticker should tick 3 times
 # before we stop it.
 $ go run tickers.go
-Tick at 2012-09-23 11:29:56.487625 -0700 PDT
-Tick at 2012-09-23 11:29:56.988063 -0700 PDT
-Tick at 2012-09-23 11:29:57.488076 -0700 PDT
+Tick at 2012-09-23 11:29:56.487625 -0700 PDT # RE: Tick at \d{4}-\d\d-\d\d \d\d:\d\d:\d\d(\.\d+)? [-+]\d{4} \w+( m=[-+][\d.]+)?
+Tick at 2012-09-23 11:29:56.988063 -0700 PDT # RE: Tick at \d{4}-\d\d-\d\d \d\d:\d\d:\d\d(\.\d+)? [-+]\d{4} \w+( m=[-+][\d.]+)?
+Tick at 2012-09-23 11:29:57.488076 -0700 PDT # RE: Tick at \d{4}-\d\d-\d\d \d\d:\d\d:\d\d(\.\d+)? [-+]\d{4} \w+( m=[-+][\d.]+)?
 Ticker stopped
@@ -1,9 +1,9 @@
 $ go run time-formatting-parsing.go 
-2014-04-15T18:00:15-07:00
-2012-11-01 22:08:41 +0000 +0000
-6:00PM
-Tue Apr 15 18:00:15 2014
-2014-04-15T18:00:15.161182-07:00
+2014-04-15T18:00:15-07:00 # RE: \d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(Z|[-+]\d\d:\d\d)
+2012-11-01 22:08:41 +0000 UTC
+6:00PM # RE: \d\d?:\d\d[AP]M
+Tue Apr 15 18:00:15 2014 # RE: \w{3} \w{3} [ \d]\d \d\d:\d\d:\d\d \d{4}
+2014-04-15T18:00:15.161182-07:00 # RE: \d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(\.\d+)?[-+]\d\d:\d\d
 0000-01-01 20:41:00 +0000 UTC
-2014-04-15T18:00:15-00:00
+2014-04-15T18:00:15-00:00 # RE: \d{4}-\d\d-\d\dT\d\d:\d\d:\d\d-00:00
 parsing time "8:41PM" as "Mon Jan _2 15:04:05 2006": ...
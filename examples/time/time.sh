@@ -1,5 +1,5 @@
 $ go run time.go
-2012-10-31 15:50:13.793654 +0000 UTC
+2012-10-31 15:50:13.793654 +0000 UTC # RE: \d{4}-\d\d-\d\d \d\d:\d\d:\d\d(\.\d+)? [-+]\d{4} \w+( m=[-+][\d.]+)?
 2009-11-17 20:34:58.651387237 +0000 UTC
 2009
 November
@@ -13,13 +13,13 @@ Tuesday
 true
 false
 false
-25891h15m15.142266763s
-25891.25420618521
-1.5534752523711128e+06
-9.320851514226677e+07
-93208515142266763
-2012-10-31 15:50:13.793654 +0000 UTC
-2006-12-05 01:19:43.509120474 +0000 UTC
+25891h15m15.142266763s # RE: \d+h\d+m[\d.]+s
+25891.25420618521 # RE: [\d.]+
+1.5534752523711128e+06 # RE: [\d.]+(e\+\d+)?
+9.320851514226677e+07 # RE: [\d.]+(e\+\d+)?
+93208515142266763 # RE: \d+
+2012-10-31 15:50:13.793654 +0000 UTC # RE: \d{4}-\d\d-\d\d \d\d:\d\d:\d\d(\.\d+)? [-+]\d{4} \w+( m=[-+][\d.]+)?
+2006-12-05 01:19:43.509120474 +0000 UTC # RE: \d{4}-\d\d-\d\d \d\d:\d\d:\d\d(\.\d+)? [-+]\d{4} \w+( m=[-+][\d.]+)?
 
 # Next we'll look at the related idea of time relative to
 # the Unix epoch.
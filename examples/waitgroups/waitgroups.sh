@@ -1,14 +1,14 @@
 $ go run waitgroups.go
-Worker 5 starting
-Worker 3 starting
-Worker 4 starting
-Worker 1 starting
-Worker 2 starting
-Worker 4 done
-Worker 1 done
-Worker 2 done
-Worker 5 done
-Worker 3 done
+Worker 5 starting # RE: Worker \d starting
+Worker 3 starting # RE: Worker \d starting
+Worker 4 starting # RE: Worker \d starting
+Worker 1 starting # RE: Worker \d starting
+Worker 2 starting # RE: Worker \d starting
+Worker 4 done # RE: Worker \d done
+Worker 1 done # RE: Worker \d done
+Worker 2 done # RE: Worker \d done
+Worker 5 done # RE: Worker \d done
+Worker 3 done # RE: Worker \d done
 
 # The order of workers starting up and finishing
 # is likely to be different for each invocation.
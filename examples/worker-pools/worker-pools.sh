@@ -3,15 +3,17 @@
 # despite doing about 5 seconds of total work because
 # there are 3 workers operating concurrently.
 $ time go run worker-pools.go 
-worker 1 started  job 1
-worker 2 started  job 2
-worker 3 started  job 3
-worker 1 finished job 1
-worker 1 started  job 4
-worker 2 finished job 2
-worker 2 started  job 5
-worker 3 finished job 3
-worker 1 finished job 4
-worker 2 finished job 5
+worker 1 started  job 1 # RE: worker \d started  job \d
+worker 2 started  job 2 # RE: worker \d started  job \d
+worker 3 started  job 3 # RE: worker \d started  job \d
+worker 1 finished job 1 # RE: worker \d finished job \d
+worker 1 started  job 4 # RE: worker \d started  job \d
+worker 2 finished job 2 # RE: worker \d finished job \d
+worker 2 started  job 5 # RE: worker \d started  job \d
+worker 3 finished job 3 # RE: worker \d finished job \d
+worker 1 finished job 4 # RE: worker \d finished job \d
+worker 2 finished job 5 # RE: worker \d finished job \d
 
-real	0m2.358s
+real	0m2.358s # RE: real\s+0m2\.\d+s
+user	0m0.102s # RE: user\s+\d+m[\d.]+s
+sys	0m0.051s # RE: sys\s+\d+m[\d.]+s
@@ -0,0 +1,99 @@
+/*
+* Search over search.json, the index that tools/generate builds from the
+* docs and code of every example. Everything happens in the browser, so the
+* site stays static.
+*/
+
+(function() {
+    var input = document.getElementById('search');
+    var results = document.getElementById('search-results');
+    if (!input || !results) {
+        return;
+    }
+    var index = null;
+    var terms = null;
+
+    function load(callback) {
+        if (index) {
+            callback();
+            return;
+        }
+        var request = new XMLHttpRequest();
+        request.open('GET', 'search.json');
+        request.onload = function() {
+            if (request.status == 200) {
+                index = JSON.parse(request.responseText);
+                terms = Object.keys(index.terms);
+                callback();
+            }
+        };
+        request.send();
+    }
+
+    // Words match every indexed term they're a prefix of.
+    function lookup(word) {
+        var found = {};
+        terms.forEach(function(term) {
+            if (term.lastIndexOf(word, 0) === 0) {
+                index.terms[term].forEach(function(id) { found[id] = true; });
+            }
+        });
+        return found;
+    }
+
+    // A segment is a result if it matches all words of the query.
+    function search(query) {
+        var words = query.toLowerCase().split(/[^\w.\/À-￿]+/).map(function(word) {
+            return word.replace(/^[.\/]+|[.\/]+$/g, '');
+        }).filter(function(word) { return word.length > 1; });
+        if (words.length == 0) {
+            return [];
+        }
+        var hits = null;
+        words.forEach(function(word) {
+            var found = lookup(word);
+            if (hits === null) {
+                hits = found;
+                return;
+            }
+            for (var id in hits) {
+                if (!found[id]) {
+                    delete hits[id];
+                }
+            }
+        });
+        return Object.keys(hits).map(Number).sort(function(a, b) { return a - b; }).slice(0, 20);
+    }
+
+    function show() {
+        var ids = search(input.value);
+        results.innerHTML = '';
+        ids.forEach(function(id) {
+            var seg = index.segs[id];
+            var page = index.pages[seg[0]];
+            var link = document.createElement('a');
+            link.href = page[0] + '#' + seg[1];
+            var name = document.createElement('strong');
+            name.textContent = page[1];
+            link.appendChild(name);
+            link.appendChild(document.createTextNode(' ' + seg[2]));
+            var item = document.createElement('li');
+            item.appendChild(link);
+            results.appendChild(item);
+        });
+        results.style.display = ids.length ? 'block' : 'none';
+    }
+
+    input.addEventListener('input', function() { load(show); });
+    input.addEventListener('keydown', function(e) {
+        if (e.key == 'Enter') {
+            var first = results.querySelector('a');
+            if (first) {
+                window.location.href = first.href;
+            }
+        } else if (e.key == 'Escape') {
+            input.value = '';
+            results.style.display = 'none';
+        }
+    });
+})();
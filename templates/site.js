@@ -15,3 +15,20 @@ var clipboard = new Clipboard('.copy', {
         return codeLines.filter(function(cL) { return cL != '' }).join("\n").replace(/\n$/, '');
     }
 });
+
+/*
+* Pointing at a docs link to a highlight group, like [the loop](#hl-loop),
+* emphasizes the lines of the group.
+*/
+
+Array.prototype.forEach.call(document.querySelectorAll('a[href^="#hl-"]'), function(link) {
+    var name = link.getAttribute('href').slice(4);
+    var lines = document.querySelectorAll('[data-hl="' + name + '"]');
+    function toggle(on) {
+        Array.prototype.forEach.call(lines, function(line) {
+            line.classList.toggle('hl-active', on);
+        });
+    }
+    link.addEventListener('mouseenter', function() { toggle(true); });
+    link.addEventListener('mouseleave', function() { toggle(false); });
+});
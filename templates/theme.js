@@ -0,0 +1,50 @@
+/*
+* Light/dark theme switch. Without a saved choice the site follows the
+* system's prefers-color-scheme; the footer toggle saves an explicit choice in
+* localStorage. This script is loaded in <head> so the saved theme applies
+* before the page is first painted.
+*/
+
+(function() {
+    var root = document.documentElement;
+
+    function saved() {
+        try {
+            return localStorage.getItem('theme');
+        } catch (e) {
+            return null;
+        }
+    }
+
+    function current() {
+        var theme = root.getAttribute('data-theme');
+        if (theme) {
+            return theme;
+        }
+        var dark = window.matchMedia && window.matchMedia('(prefers-color-scheme: dark)').matches;
+        return dark ? 'dark' : 'light';
+    }
+
+    var theme = saved();
+    if (theme === 'light' || theme === 'dark') {
+        root.setAttribute('data-theme', theme);
+    }
+
+    document.addEventListener('DOMContentLoaded', function() {
+        var toggle = document.getElementById('theme-toggle');
+        if (!toggle) {
+            return;
+        }
+        toggle.addEventListener('click', function(e) {
+            e.preventDefault();
+            var next = current() === 'dark' ? 'light' : 'dark';
+            root.setAttribute('data-theme', next);
+            try {
+                localStorage.setItem('theme', next);
+            } catch (e) {
+                // Private browsing may disallow storage; the choice then
+                // lasts only for this page.
+            }
+        });
+    });
+})();
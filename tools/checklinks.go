@@ -0,0 +1,226 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// checklinks finds links and assets in the generated site that lead
+// nowhere. With -external it also requests every external URL, which
+// includes those of the docs of the examples. The links in the docs to other
+// pages of the site are checked by generate -checklinks, at the lines of the
+// sources they're on; tools/checklinks runs both.
+
+var external = flag.Bool("external", false, "also check external URLs")
+var timeout = flag.Duration("timeout", 10*time.Second, "timeout of each external request")
+
+// problem is a broken link, reported as file:line.
+type problem struct {
+	path string
+	line int
+	msg  string
+}
+
+func (p problem) String() string {
+	return fmt.Sprintf("%s:%d: %s", p.path, p.line, p.msg)
+}
+
+// link is an external URL and where it was found.
+type link struct {
+	url  string
+	path string
+	line int
+}
+
+func check(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "checklinks:", err)
+		os.Exit(1)
+	}
+}
+
+var refPat = regexp.MustCompile(`\b(href|src)="([^"]*)"`)
+var idPat = regexp.MustCompile(`\bid="([^"]*)"`)
+
+// isPage reports whether a file of the generated site is an HTML page.
+// Example pages have no extension, so that their URLs don't either.
+func isPage(name string) bool {
+	ext := filepath.Ext(name)
+	return ext == "" || ext == ".html"
+}
+
+// checkSite crawls the generated site in dir and checks that every href and
+// src in its pages leads to a file of the site, and that fragments name an
+// element of the page they point at.
+func checkSite(dir string) ([]problem, []link) {
+	pages := make(map[string]string)
+	anchors := make(map[string]map[string]bool)
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !isPage(p) {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		dat, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		pages[rel] = string(dat)
+		anchors[rel] = make(map[string]bool)
+		for _, m := range idPat.FindAllStringSubmatch(pages[rel], -1) {
+			anchors[rel][html.UnescapeString(m[1])] = true
+		}
+		return nil
+	})
+	check(err)
+
+	var names []string
+	for name := range pages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var problems []problem
+	var links []link
+	for _, name := range names {
+		page := pages[name]
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		for _, loc := range refPat.FindAllStringSubmatchIndex(page, -1) {
+			line := strings.Count(page[:loc[0]], "\n") + 1
+			attr := page[loc[2]:loc[3]]
+			ref := html.UnescapeString(page[loc[4]:loc[5]])
+			u, err := url.Parse(ref)
+			if err != nil {
+				problems = append(problems, problem{p, line, fmt.Sprintf("malformed %s %q", attr, ref)})
+				continue
+			}
+			if u.Scheme == "http" || u.Scheme == "https" {
+				links = append(links, link{ref, p, line})
+				continue
+			}
+			if u.Scheme != "" || u.Host != "" || ref == "" {
+				continue
+			}
+			target := name
+			if u.Path != "" {
+				if strings.HasPrefix(u.Path, "/") {
+					target = path.Clean(u.Path[1:])
+				} else {
+					target = path.Join(path.Dir(name), u.Path)
+				}
+				if strings.HasSuffix(u.Path, "/") || target == "." {
+					target = path.Join(target, "index.html")
+				}
+			}
+			if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(target))); err != nil {
+				what := "broken link to"
+				if attr == "src" {
+					what = "missing asset"
+				}
+				problems = append(problems, problem{p, line, fmt.Sprintf("%s %q", what, ref)})
+				continue
+			}
+			if u.Fragment != "" && anchors[target] != nil && !anchors[target][u.Fragment] {
+				problems = append(problems, problem{p, line, fmt.Sprintf("broken anchor %q", ref)})
+			}
+		}
+	}
+	return problems, links
+}
+
+// checkExternal requests each distinct URL once and reports every place
+// that links to one that fails. Some servers don't support HEAD, so a failed
+// HEAD is retried as a GET.
+func checkExternal(client *http.Client, links []link) []problem {
+	byURL := make(map[string][]link)
+	for _, l := range links {
+		byURL[l.url] = append(byURL[l.url], l)
+	}
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		problems []problem
+	)
+	sem := make(chan struct{}, 4)
+	for u, uses := range byURL {
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			status := fetchStatus(client, u)
+			if status == "" {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, l := range uses {
+				problems = append(problems, problem{l.path, l.line, fmt.Sprintf("%s: %s", l.url, status)})
+			}
+		})
+	}
+	wg.Wait()
+	return problems
+}
+
+// fetchStatus returns why the URL is broken, or "" if it isn't.
+func fetchStatus(client *http.Client, u string) string {
+	var status string
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequest(method, u, nil)
+		if err != nil {
+			return err.Error()
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			status = err.Error()
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 400 {
+			return ""
+		}
+		status = resp.Status
+	}
+	return status
+}
+
+func sortProblems(problems []problem) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].path != problems[j].path {
+			return problems[i].path < problems[j].path
+		}
+		return problems[i].line < problems[j].line
+	})
+}
+
+func main() {
+	flag.Parse()
+	siteDir := "public"
+	if flag.NArg() > 0 {
+		siteDir = flag.Arg(0)
+	}
+	problems, links := checkSite(siteDir)
+	if *external {
+		client := &http.Client{Timeout: *timeout}
+		problems = append(problems, checkExternal(client, links)...)
+	}
+	sortProblems(problems)
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// messages returns the problems as "file:line: msg" with paths relative to
+// root, for comparison.
+func messages(root string, problems []problem) []string {
+	sortProblems(problems)
+	var msgs []string
+	for _, p := range problems {
+		rel, _ := filepath.Rel(root, p.path)
+		p.path = filepath.ToSlash(rel)
+		msgs = append(msgs, p.String())
+	}
+	return msgs
+}
+
+func TestCheckSite(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"index.html": "<a href=\"hello\">hello</a>\n<a href=\"hello#s0-1\">seg</a>\n" +
+			"<a href=\"hello#s9-9\">gone</a>\n<a href=\"goodbye\">bye</a>\n",
+		"hello": "<link rel=stylesheet href=\"site.css\">\n<img src=\"play.png\">\n" +
+			"<tr id=\"s0-1\"><a href=\"./\">home</a> <a href=\"#\">top</a>\n" +
+			"<a href=\"https://example.com/x\">ext</a>\n",
+		"site.css": "",
+	})
+	problems, links := checkSite(root)
+	want := []string{
+		"hello:2: missing asset \"play.png\"",
+		"index.html:3: broken anchor \"hello#s9-9\"",
+		"index.html:4: broken link to \"goodbye\"",
+	}
+	if got := messages(root, problems); !reflect.DeepEqual(got, want) {
+		t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if len(links) != 1 || links[0].url != "https://example.com/x" || links[0].line != 4 {
+		t.Errorf("external links = %+v, want https://example.com/x at line 4", links)
+	}
+}
+
+func TestCheckExternal(t *testing.T) {
+	// A stand-in for the web: HEAD isn't allowed on /get-only, so that the
+	// fallback to GET is exercised.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/ok":
+		case r.URL.Path == "/get-only" && r.Method == http.MethodGet:
+		case r.URL.Path == "/get-only":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	links := []link{
+		{server.URL + "/ok", "a.go", 1},
+		{server.URL + "/get-only", "a.go", 2},
+		{server.URL + "/missing", "a.go", 3},
+		{server.URL + "/missing", "b.go", 7},
+	}
+	problems := checkExternal(server.Client(), links)
+	want := []string{
+		"a.go:3: " + server.URL + "/missing: 404 Not Found",
+		"b.go:7: " + server.URL + "/missing: 404 Not Found",
+	}
+	if got := messages(".", problems); !reflect.DeepEqual(got, want) {
+		t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -1,19 +1,38 @@
 package main
 
 import (
+	"archive/zip"
 	"bytes"
 	"crypto/sha1"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
 	"fmt"
+	"go/ast"
+	"go/build"
+	"go/doc"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"go/version"
+	"html"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"text/template"
+	"time"
 
 	"github.com/alecthomas/chroma/v2"
-	"github.com/alecthomas/chroma/v2/formatters/html"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
 	"github.com/alecthomas/chroma/v2/lexers"
 	"github.com/alecthomas/chroma/v2/styles"
 
@@ -21,23 +40,126 @@ import (
 )
 
 // siteDir is the target directory into which the HTML gets generated. Its
-// default is set here but can be changed by an argument passed into the
-// program.
+// default comes from the site config but can be changed by an argument passed
+// into the program.
 var siteDir = "./public"
 
+var configPath = flag.String("config", "site.json", "site configuration file")
+
+// siteConfig holds what differs between deployments of the site: its name,
+// authors, links and playground. It is read from site.json and passed to
+// every template as .Site. Flags given on the command line win over it.
+type siteConfig struct {
+	Title string `json:"title"`
+	// Description is the index page's meta description.
+	Description string       `json:"description"`
+	Authors     []siteAuthor `json:"authors"`
+	SourceURL   string       `json:"sourceURL"`
+	LicenseURL  string       `json:"licenseURL"`
+	// PlaygroundURL is the prefix of "Run code" links, followed by the hash
+	// the share backend returned.
+	PlaygroundURL string `json:"playgroundURL"`
+	ShareURL      string `json:"shareURL"`
+	// BaseURL is where the site is published. Canonical links and
+	// sitemap.xml are only written when it is set.
+	BaseURL   string `json:"baseURL"`
+	SiteDir   string `json:"siteDir"`
+	Style     string `json:"style"`
+	DarkStyle string `json:"darkStyle"`
+}
+
+// URL returns the absolute URL of the page at path in the published site,
+// or "" without a base URL.
+func (c *siteConfig) URL(path string) string {
+	if c.BaseURL == "" {
+		return ""
+	}
+	return strings.TrimSuffix(c.BaseURL, "/") + "/" + path
+}
+
+type siteAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// site is the loaded configuration; keys missing from the file keep these
+// defaults.
+var site = &siteConfig{
+	Title:       "Go by Example",
+	Description: "Go by Example is a hands-on introduction to Go using annotated example programs.",
+	Authors: []siteAuthor{
+		{"Mark McGranaghan", "https://markmcgranaghan.com"},
+		{"Eli Bendersky", "https://eli.thegreenplace.net"},
+	},
+	SourceURL:     "https://github.com/mmcgrana/gobyexample",
+	LicenseURL:    "https://github.com/mmcgrana/gobyexample#license",
+	PlaygroundURL: "https://go.dev/play/p/",
+	ShareURL:      defaultShareURL,
+	BaseURL:       "https://gobyexample.com/",
+	SiteDir:       "./public",
+	Style:         "gobyexample",
+	DarkStyle:     "gobyexample-dark",
+}
+
+// offline, when set, keeps generation from talking to the playground at all.
+// Examples whose code changed keep their stale .hash file and are flagged as
+// having an outdated playground link.
+var offline = flag.Bool("offline", false, "don't contact the playground; keep stale links")
+
+var shareName = flag.String("share", "play", "playground share backend: play or hash")
+var shareURL = flag.String("share-url", site.ShareURL, "share endpoint used by the play backend")
+
+var force = flag.Bool("force", false, "ignore the render cache and regenerate everything")
+var cacheDir = flag.String("cache", ".cache/generate", "directory of the render cache")
+
+var jobs = flag.Int("j", runtime.NumCPU(), "number of examples parsed and rendered in parallel")
+var shareJobs = flag.Int("share-j", 2, "number of concurrent playground share requests")
+
+// keepGoing renders the examples without problems even when others have
+// some. The problems are still reported and still fail the run.
+var keepGoing = flag.Bool("keep-going", false, "render valid examples despite problems in others")
+
+var checklist = flag.Bool("checklist", false, "regenerate examples-index.md instead of the site")
+
+// checkLinks checks the links in the docs of the examples as the site shows
+// them. tools/checklinks runs it before checking the generated site.
+var checkLinks = flag.Bool("checklinks", false, "check the links in the docs of the examples instead of generating the site")
+
+var epubPath = flag.String("epub", "", "also write all examples as an EPUB book to this file")
+var markdownDir = flag.String("markdown", "", "also write all examples as Markdown files to this directory")
+
+// styleName and darkStyleName are the chroma styles highlight.css is
+// generated from. The dark one applies when the reader's system prefers dark
+// mode or they pick it with the toggle in the footer.
+var styleName = flag.String("style", site.Style, "chroma style for syntax highlighting")
+var darkStyleName = flag.String("dark-style", site.DarkStyle, "chroma style for dark mode, or empty for none")
+
+// themeDir overlays templates/: a template or asset found in the theme wins
+// over the one with the same name in templates/, and everything under the
+// theme's static/ folder is copied into the site as is.
+var themeDir = flag.String("theme", "", "directory of templates and assets overriding those in templates/")
+
 func verbose() bool {
 	return len(os.Getenv("VERBOSE")) > 0
 }
 
+// check aborts generation on errors that aren't the fault of an example,
+// like an unwritable output directory. Problems in examples are collected and
+// reported instead; see problem.
 func check(err error) {
 	if err != nil {
-		panic(err)
+		fatalf("%v", err)
 	}
 }
 
+func fatalf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "generate: "+format+"\n", args...)
+	os.Exit(1)
+}
+
 func isDir(path string) bool {
-	fileStat, _ := os.Stat(path)
-	return fileStat.IsDir()
+	fileStat, err := os.Stat(path)
+	return err == nil && fileStat.IsDir()
 }
 
 func ensureDir(dir string) {
@@ -48,7 +170,16 @@ func ensureDir(dir string) {
 func copyFile(src, dst string) {
 	dat, err := os.ReadFile(src)
 	check(err)
-	err = os.WriteFile(dst, dat, 0644)
+	writeIfChanged(dst, dat)
+}
+
+// writeIfChanged writes dat to path unless the file already has exactly that
+// content, so unchanged outputs keep their timestamps.
+func writeIfChanged(path string, dat []byte) {
+	if old, err := os.ReadFile(path); err == nil && bytes.Equal(old, dat) {
+		return
+	}
+	err := os.WriteFile(path, dat, 0644)
 	check(err)
 }
 
@@ -80,13 +211,85 @@ func mustGlob(glob string) []string {
 	return paths
 }
 
-func whichLexer(path string) string {
-	if strings.HasSuffix(path, ".go") {
-		return "go"
-	} else if strings.HasSuffix(path, ".sh") {
-		return "console"
+// fileType says how to show a kind of example source file.
+type fileType struct {
+	// Name is the language of the file in Markdown code fences, and what
+	// Seg.Lexer is set to.
+	Name string
+	// Lexer is the chroma lexer highlighting the file's code.
+	Lexer chroma.Lexer
+	// Comment starts a line comment. Comment lines are docs, rendered as
+	// Markdown next to the code. Without it, a file is all code.
+	Comment string
+	// BlockComment holds the delimiters of block comments. Block comments
+	// that take up whole lines are docs too.
+	BlockComment [2]string
+	docsPat      *regexp.Regexp
+	// hlPat matches a highlight marker at the end of a line of code.
+	hlPat *regexp.Regexp
+	// setupPat matches the lines that begin and end a setup region.
+	setupPat *regexp.Regexp
+}
+
+func newFileType(name string, lexer chroma.Lexer, comment string) *fileType {
+	if lexer == nil {
+		panic("no chroma lexer for " + name)
+	}
+	ft := &fileType{Name: name, Lexer: chroma.Coalesce(lexer), Comment: comment}
+	if comment != "" {
+		ft.docsPat = regexp.MustCompile(`^\s*` + regexp.QuoteMeta(comment) + `(\s|$)`)
+		ft.hlPat = regexp.MustCompile(`\s*` + regexp.QuoteMeta(comment) + ` HL(?::([\w-]+))?\s*$`)
+		ft.setupPat = regexp.MustCompile(`^\s*` + regexp.QuoteMeta(comment) + ` (END )?SETUP\s*$`)
+	}
+	return ft
+}
+
+func (ft *fileType) withBlockComment(start, end string) *fileType {
+	ft.BlockComment = [2]string{start, end}
+	return ft
+}
+
+// fileTypes maps the extensions of example source files, or their whole
+// names, to their file type. Files of other types are reported as
+// unsupported.
+var fileTypes = map[string]*fileType{
+	".go":   newFileType("go", lexers.Get("go"), "//").withBlockComment("/*", "*/"),
+	".sh":   newFileType("console", SimpleShellOutputLexer, "#"),
+	".mod":  newFileType("go-mod", lexers.Get("go"), "//"),
+	".json": newFileType("json", lexers.Get("json"), ""),
+	".yaml": newFileType("yaml", lexers.Get("yaml"), "#"),
+	".yml":  newFileType("yaml", lexers.Get("yaml"), "#"),
+	".txt":  newFileType("text", lexers.Get("plaintext"), ""),
+	".sql":  newFileType("sql", lexers.Get("sql"), "--").withBlockComment("/*", "*/"),
+	".http": newFileType("http", lexers.Get("http"), "#"),
+	".tmpl": newFileType("go-text-template", lexers.Get("go-text-template"), ""),
+	// Data files of an example, like the ones embed-directive embeds.
+	".hash": newFileType("text", lexers.Get("plaintext"), ""),
+}
+
+// fileTypeOf returns the file type of an example source file, or nil if the
+// type isn't supported.
+func fileTypeOf(path string) *fileType {
+	if ft, ok := fileTypes[filepath.Base(path)]; ok {
+		return ft
+	}
+	return fileTypes[filepath.Ext(path)]
+}
+
+// forEach calls fn(i) for every i in [0, n), running at most *jobs calls at
+// once. Callers store results by index so that output order never depends on
+// scheduling.
+func forEach(n int, fn func(i int)) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(*jobs, 1))
+	for i := range n {
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			fn(i)
+		})
 	}
-	panic("No lexer for " + path)
+	wg.Wait()
 }
 
 func debug(msg string) {
@@ -95,7 +298,6 @@ func debug(msg string) {
 	}
 }
 
-var docsPat = regexp.MustCompile(`^(\s*(\/\/|#)\s|\s*\/\/$)`)
 var dashPat = regexp.MustCompile(`\-+`)
 
 // Seg is a segment of an example
@@ -103,56 +305,425 @@ type Seg struct {
 	Docs, DocsRendered              string
 	Code, CodeRendered, CodeForJs   string
 	CodeEmpty, CodeLeading, CodeRun bool
+	Anchor, Lexer                   string
+	Highlights                      []Highlight
+	// Setup is set on the code of a setup region, which is shown collapsed
+	// and left out of the copied code.
+	Setup bool
+}
+
+// Highlight is a line of code marked with a trailing "// HL" comment, or
+// "// HL:name" to make it part of a group that docs can link to as
+// "#hl-name". The marker itself is never shown or shared.
+type Highlight struct {
+	Line int // in Seg.Code, from 1
+	Name string
 }
 
 // Example is info extracted from an example file
 type Example struct {
 	ID, Name                    string
+	Description                 string
+	Section, SectionID          string
 	GoCode, GoCodeHash, URLHash string
-	Segs                        [][]*Seg
-	PrevExample                 *Example
-	NextExample                 *Example
+	URLOutdated                 bool
+	// GoVersion is the oldest Go that can build the example, like "1.23",
+	// and GoVersionReason what needs it. Both are empty if Go 1 can.
+	GoVersion, GoVersionReason string
+	// Files are the paths of the example's source files relative to its
+	// directory, in the order they are shown; Segs has the segments of each.
+	Files       []string
+	Segs        [][]*Seg
+	Notes       []*Note
+	PrevExample *Example
+	NextExample *Example
+
+	// Front matter, from meta.json or a comment block at the top of the
+	// example's first file. Related examples and prerequisites are IDs, and
+	// linkExamples turns them into links: unlike pointers to the examples,
+	// links can be serialized for the page cache.
+	Tags                            []pageLink
+	Difficulty, Summary             string
+	Related, Prerequisites          []string
+	RelatedLinks, PrerequisiteLinks []pageLink
+
+	line     int // in examples.txt
+	metaPos  map[string]metaPos
+	problems []problem
+	warnings []string
 }
 
-func parseHashFile(sourcePath string) (string, string) {
-	lines := readLines(sourcePath)
-	return lines[0], lines[1]
+// pageLink is a link to another page of the site.
+type pageLink struct {
+	ID, Name string
+}
+
+var tagIDPat = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// tagID returns the ID of the page listing the examples with a tag. It
+// keeps only letters, digits and dashes, since it's a file name and a URL.
+func tagID(tag string) string {
+	id := tagIDPat.ReplaceAllString(exampleID(tag), "")
+	return "tag-" + strings.Trim(dashPat.ReplaceAllString(id, "-"), "-")
+}
+
+// Section is a run of consecutive examples that examples.txt groups under a
+// "## Title" heading. Examples before the first heading are in a section
+// without a title.
+type Section struct {
+	ID, Title string
+	Examples  []*Example
+}
+
+// Note is a companion markdown file of an example, rendered as a page of its
+// own and listed under "Further reading" on the example's page.
+type Note struct {
+	ID, Title, Rendered    string
+	Description            string
+	ExampleID, ExampleName string
+
+	path string // of the markdown file
+}
+
+var mdHeadingPat = regexp.MustCompile(`(?m)^#+\s.*$`)
+var sentenceEndPat = regexp.MustCompile(`[.!?](\s|$)`)
+var mdEmphasisPat = regexp.MustCompile(`\b_([^_]+)_\b`)
+var htmlTagPat = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+
+// firstSentence returns the first sentence of the first paragraph of a
+// markdown text, without markup, for use as a page description.
+func firstSentence(md string) string {
+	for _, para := range strings.Split(mdHeadingPat.ReplaceAllString(md, ""), "\n\n") {
+		if strings.HasPrefix(strings.TrimSpace(para), "```") {
+			continue
+		}
+		text := htmlTagPat.ReplaceAllString(searchMarkupPat.ReplaceAllString(para, ""), "")
+		text = mdEmphasisPat.ReplaceAllString(text, "$1")
+		text = strings.Join(strings.Fields(text), " ")
+		if text == "" {
+			continue
+		}
+		if loc := sentenceEndPat.FindStringIndex(text); loc != nil {
+			text = text[:loc[0]+1]
+		}
+		return text
+	}
+	return ""
+}
+
+var mdTitlePat = regexp.MustCompile(`(?m)^#\s+(.+)$`)
+var mdLinkPat = regexp.MustCompile(`\]\(([^)#\s]+\.md)(#[^)\s]*)?\)`)
+
+// parseNotes renders the markdown files among an example's sources. Pages are
+// named after the example, e.g. CONCEPTS.md in generics becomes
+// generics-concepts, because the site directory is kept flat. Links between
+// the notes are rewritten to point at the rendered pages.
+func parseNotes(exampleID, exampleName string, paths []string) []*Note {
+	ids := make(map[string]string)
+	for _, path := range paths {
+		base := strings.ToLower(strings.TrimSuffix(filepath.Base(path), ".md"))
+		base = strings.TrimPrefix(base, exampleID+"-")
+		ids[strings.ToLower(filepath.Base(path))] = exampleID + "-" + base
+	}
+	var notes []*Note
+	for _, path := range paths {
+		src := mustReadFile(path)
+		src = mdLinkPat.ReplaceAllStringFunc(src, func(link string) string {
+			m := mdLinkPat.FindStringSubmatch(link)
+			if id, ok := ids[strings.ToLower(m[1])]; ok {
+				return "](" + id + m[2] + ")"
+			}
+			return link
+		})
+		note := Note{
+			ID:          ids[strings.ToLower(filepath.Base(path))],
+			Title:       filepath.Base(path),
+			Rendered:    markdown(src),
+			Description: firstSentence(src),
+			ExampleID:   exampleID,
+			ExampleName: exampleName,
+			path:        path,
+		}
+		if m := mdTitlePat.FindStringSubmatch(src); m != nil {
+			note.Title = strings.TrimSpace(m[1])
+		}
+		notes = append(notes, &note)
+	}
+	return notes
+}
+
+// problem is something wrong with the sources of an example, reported to its
+// author as file:line instead of aborting generation.
+type problem struct {
+	path string
+	line int
+	msg  string
+}
+
+func (p problem) String() string {
+	if p.line > 0 {
+		return fmt.Sprintf("%s:%d: %s", p.path, p.line, p.msg)
+	}
+	return fmt.Sprintf("%s: %s", p.path, p.msg)
+}
+
+func (e *Example) problemf(path string, line int, format string, args ...any) {
+	e.problems = append(e.problems, problem{path, line, fmt.Sprintf(format, args...)})
+}
+
+// metaProblemf reports a problem with the value of a front matter key where
+// the key was set.
+func (e *Example) metaProblemf(key string, format string, args ...any) {
+	pos := e.metaPos[key]
+	e.problemf(pos.path, pos.line, format, args...)
+}
+
+const defaultShareURL = "https://play.golang.org/share"
+
+// shareBackend uploads example code to a playground and returns the key
+// under which it can be found at https://go.dev/play/p/<key>.
+type shareBackend interface {
+	share(code string) (string, error)
+	// local reports whether the keys only exist locally. They aren't saved
+	// to the .hash files, and links with them are marked outdated.
+	local() bool
+}
+
+// playShare posts code to the share endpoint of the go.dev playground, or to
+// any local stand-in that speaks the same protocol.
+type playShare struct {
+	url string
 }
 
-func resetURLHashFile(codehash, code, sourcePath string) string {
+func (p playShare) share(code string) (string, error) {
 	if verbose() {
-		fmt.Println("  Sending request to play.golang.org")
+		fmt.Println("  Sending request to " + p.url)
+	}
+	resp, err := http.Post(p.url, "text/plain", strings.NewReader(code))
+	if err != nil {
+		return "", err
 	}
-	payload := strings.NewReader(code)
-	resp, err := http.Post("https://play.golang.org/share", "text/plain", payload)
-	check(err)
 	defer resp.Body.Close()
 	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s", p.url, resp.Status)
+	}
+	return string(body), nil
+}
+
+func (playShare) local() bool { return false }
+
+// hashShare derives a deterministic key from the code itself without any
+// network access. The resulting links don't resolve on the real playground;
+// it's meant for reproducible local builds and testing.
+type hashShare struct{}
+
+func (hashShare) share(code string) (string, error) {
+	return sha1Sum(code)[:11], nil
+}
+
+func (hashShare) local() bool { return true }
+
+// limitedShare caps the number of in-flight requests to another backend,
+// independently of how many examples are processed in parallel.
+type limitedShare struct {
+	shareBackend
+	sem chan struct{}
+}
+
+func (l limitedShare) share(code string) (string, error) {
+	l.sem <- struct{}{}
+	defer func() { <-l.sem }()
+	return l.shareBackend.share(code)
+}
+
+func newShareBackend(name string) shareBackend {
+	switch name {
+	case "play":
+		return playShare{url: *shareURL}
+	case "hash":
+		return hashShare{}
+	}
+	fatalf("unknown share backend %q", name)
+	return nil
+}
+
+// renderCache is a content-addressed store of rendering results. Keys are
+// hashes of everything that went into a result, so entries never need to be
+// invalidated; stale ones are simply not looked up anymore.
+type renderCache struct {
+	dir string
+	// base is mixed into every key. It covers the generator itself and the
+	// chroma style, which affect every result.
+	base string
+}
+
+func newRenderCache(dir string) *renderCache {
+	base := sha1Sum(mustReadFile("tools/generate.go") + "\x00" + *styleName + "\x00" + runtime.Version())
+	return &renderCache{dir: dir, base: base}
+}
+
+func (c *renderCache) key(parts ...string) string {
+	return sha1Sum(c.base + "\x00" + strings.Join(parts, "\x00"))
+}
+
+func (c *renderCache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+func (c *renderCache) get(key string) ([]byte, bool) {
+	if *force {
+		return nil, false
+	}
+	dat, err := os.ReadFile(c.path(key))
+	return dat, err == nil
+}
+
+// put stores dat under key. It's written to a temporary file first and
+// renamed into place, so an interrupted run never leaves a truncated entry.
+func (c *renderCache) put(key string, dat []byte) {
+	path := c.path(key)
+	ensureDir(filepath.Dir(path))
+	f, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	check(err)
+	_, err = f.Write(dat)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
 	check(err)
-	urlkey := string(body)
+}
+
+func (c *renderCache) getJSON(key string, v any) bool {
+	dat, ok := c.get(key)
+	return ok && json.Unmarshal(dat, v) == nil
+}
+
+func (c *renderCache) putJSON(key string, v any) {
+	dat, err := json.Marshal(v)
+	check(err)
+	c.put(key, dat)
+}
+
+var cache *renderCache
+
+// parseHashFile returns the code hash and the playground key of a .hash
+// file, and whether it has both.
+func parseHashFile(sourcePath string) (string, string, bool) {
+	lines := readLines(sourcePath)
+	if len(lines) < 2 || lines[0] == "" || lines[1] == "" {
+		return "", "", false
+	}
+	return lines[0], lines[1], true
+}
+
+func resetURLHashFile(backend shareBackend, codehash, code, sourcePath string) (string, error) {
+	urlkey, err := backend.share(code)
+	if err != nil || backend.local() {
+		return urlkey, err
+	}
 	data := fmt.Sprintf("%s\n%s\n", codehash, urlkey)
-	os.WriteFile(sourcePath, []byte(data), 0644)
-	return urlkey
+	return urlkey, os.WriteFile(sourcePath, []byte(data), 0644)
 }
 
+// transcriptREPat matches the regexp marker that tools/transcripts matches
+// a line of expected output with instead.
+var transcriptREPat = regexp.MustCompile(`\s+# RE: .*$`)
+
 func parseSegs(sourcePath string) ([]*Seg, string) {
 	var (
 		lines  []string
 		source []string
+		marks  []*Highlight
+		setup  []bool
 	)
-	// Convert tabs to spaces for uniform rendering.
-	for _, line := range readLines(sourcePath) {
+	ft := fileTypeOf(sourcePath)
+	docsPat := ft.docsPat
+	inSetup := false
+	// Front matter is metadata, not part of the example.
+	all := readLines(sourcePath)
+	_, skip := frontMatter(ft, all)
+	for _, line := range all[skip:] {
+		// Setup markers are dropped too. The code between them stays in the
+		// source, but the page shows it collapsed. A region without an end
+		// marker runs to the end of the file.
+		if ft.setupPat != nil {
+			if m := ft.setupPat.FindStringSubmatch(line); m != nil {
+				inSetup = m[1] == ""
+				continue
+			}
+		}
+		// Highlight markers are taken out of the source, so that neither the
+		// page nor the playground shows them.
+		var mark *Highlight
+		if ft.hlPat != nil && !docsPat.MatchString(line) {
+			if m := ft.hlPat.FindStringSubmatchIndex(line); m != nil {
+				mark = &Highlight{}
+				if m[2] >= 0 {
+					mark.Name = line[m[2]:m[3]]
+				}
+				line = line[:m[0]]
+			}
+		}
+		// Regexp markers of transcripts are only for tools/transcripts.
+		if ft.Name == "console" && !docsPat.MatchString(line) {
+			line = transcriptREPat.ReplaceAllString(line, "")
+		}
+		// Convert tabs to spaces for uniform rendering.
 		lines = append(lines, strings.Replace(line, "\t", "    ", -1))
 		source = append(source, line)
+		marks = append(marks, mark)
+		setup = append(setup, inSetup)
 	}
 	segs := []*Seg{}
 	lastSeen := ""
-	for _, line := range lines {
+	for n := 0; n < len(lines); n++ {
+		line := lines[n]
+		if setup[n] {
+			// A setup region is a segment of its own, comments included.
+			start, end := n, n
+			for end < len(lines) && setup[end] {
+				end++
+			}
+			n = end - 1
+			for start < end && lines[start] == "" {
+				start++
+			}
+			for end > start && lines[end-1] == "" {
+				end--
+			}
+			if start < end {
+				seg := &Seg{Code: strings.Join(lines[start:end], "\n"), Setup: true}
+				for i := start; i < end; i++ {
+					if mark := marks[i]; mark != nil {
+						mark.Line = i - start + 1
+						seg.Highlights = append(seg.Highlights, *mark)
+					}
+				}
+				segs = append(segs, seg)
+			}
+			lastSeen = ""
+			continue
+		}
 		if line == "" {
 			lastSeen = ""
 			continue
 		}
-		matchDocs := docsPat.MatchString(line)
+		// A block comment is docs as a whole. Only the page loses it; the
+		// source keeps it.
+		block, end, isBlock := blockComment(ft, lines, n)
+		if isBlock {
+			n = end
+		}
+		matchDocs := isBlock || docsPat != nil && docsPat.MatchString(line)
 		matchCode := !matchDocs
 		newDocs := (lastSeen == "") || ((lastSeen != "docs") && (segs[len(segs)-1].Docs != ""))
 		newCode := (lastSeen == "") || ((lastSeen != "code") && (segs[len(segs)-1].Code != ""))
@@ -160,7 +731,10 @@ func parseSegs(sourcePath string) ([]*Seg, string) {
 			debug("NEWSEG")
 		}
 		if matchDocs {
-			trimmed := docsPat.ReplaceAllString(line, "")
+			trimmed := block
+			if !isBlock {
+				trimmed = docsPat.ReplaceAllString(line, "")
+			}
 			if newDocs {
 				newSeg := Seg{Docs: trimmed, Code: ""}
 				segs = append(segs, &newSeg)
@@ -181,6 +755,11 @@ func parseSegs(sourcePath string) ([]*Seg, string) {
 					lastSeg.Code = lastSeg.Code + "\n" + line
 				}
 			}
+			if mark := marks[n]; mark != nil {
+				lastSeg := segs[len(segs)-1]
+				mark.Line = strings.Count(lastSeg.Code, "\n") + 1
+				lastSeg.Highlights = append(lastSeg.Highlights, *mark)
+			}
 			debug("CODE: " + line)
 			lastSeen = "code"
 		}
@@ -188,165 +767,2168 @@ func parseSegs(sourcePath string) ([]*Seg, string) {
 	for i, seg := range segs {
 		seg.CodeEmpty = (seg.Code == "")
 		seg.CodeLeading = (i < (len(segs) - 1))
-		seg.CodeRun = strings.Contains(seg.Code, "package main")
+		seg.CodeRun = !seg.Setup && strings.Contains(seg.Code, "package main")
 	}
 	return segs, strings.Join(source, "\n")
 }
 
-func chromaFormat(code, filePath string) string {
-	lexer := lexers.Get(filePath)
-	if lexer == nil {
-		lexer = lexers.Fallback
+// blockComment returns the text of a block comment starting at lines[n],
+// and the index of the line it ends on. It only counts comments that start
+// and end their lines. The text loses the comment's own indentation, but
+// what is indented further stays so, and Markdown shows it as code. So is
+// commented-out Go code.
+func blockComment(ft *fileType, lines []string, n int) (string, int, bool) {
+	start, end := ft.BlockComment[0], ft.BlockComment[1]
+	rest := strings.TrimLeft(lines[n], " ")
+	if start == "" || !strings.HasPrefix(rest, start) {
+		return "", 0, false
 	}
-
-	if strings.HasSuffix(filePath, ".sh") {
-		lexer = SimpleShellOutputLexer
+	indent := lines[n][:len(lines[n])-len(rest)]
+	rest = strings.TrimLeft(rest[len(start):], " ")
+	var text []string
+	for i := n; i < len(lines); i++ {
+		if i > n {
+			rest = strings.TrimPrefix(lines[i], indent)
+		}
+		j := strings.Index(rest, end)
+		if j < 0 {
+			text = append(text, rest)
+			continue
+		}
+		if strings.TrimSpace(rest[j+len(end):]) != "" {
+			return "", 0, false
+		}
+		text = append(text, strings.TrimRight(rest[:j], " "))
+		docs := strings.Trim(strings.Join(text, "\n"), "\n")
+		if ft.Name == "go" && docs != "" && isGoCode(docs) {
+			docs = "```go\n" + docs + "\n```"
+		}
+		return docs, i, true
 	}
+	return "", 0, false
+}
 
-	lexer = chroma.Coalesce(lexer)
+// isGoCode reports whether src parses as Go declarations. Statements aren't
+// tried, since a lone word of prose parses as one.
+func isGoCode(src string) bool {
+	_, err := parser.ParseFile(token.NewFileSet(), "", "package p\n"+src, parser.SkipObjectResolution)
+	return err == nil
+}
 
-	style := styles.Get("swapoff")
-	if style == nil {
-		style = styles.Fallback
+func chromaFormat(code, filePath string, highlights ...Highlight) string {
+	lexer := fileTypeOf(filePath).Lexer
+	style := lookupStyle(*styleName)
+	var ranges [][2]int
+	for _, hl := range highlights {
+		ranges = append(ranges, [2]int{hl.Line, hl.Line})
 	}
-	formatter := html.New(html.WithClasses(true))
-	iterator, err := lexer.Tokenise(nil, string(code))
-	check(err)
+	formatter := chromahtml.New(chromahtml.WithClasses(true), chromahtml.HighlightLines(ranges))
 	buf := new(bytes.Buffer)
-	err = formatter.Format(buf, style, iterator)
+	err := formatter.Format(buf, style, chroma.Literator(tokenise(lexer, code)...))
 	check(err)
-	return buf.String()
+	return nameHighlights(buf.String(), highlights)
 }
 
-func parseAndRenderSegs(sourcePath string) ([]*Seg, string) {
-	segs, filecontent := parseSegs(sourcePath)
-	lexer := whichLexer(sourcePath)
-	for _, seg := range segs {
-		if seg.Docs != "" {
-			seg.DocsRendered = markdown(seg.Docs)
-		}
-		if seg.Code != "" {
-			seg.CodeRendered = chromaFormat(seg.Code, sourcePath)
+var chromaLinePat = regexp.MustCompile(`<span class="line( hl)?">`)
 
-			// adding the content to the js code for copying to the clipboard
-			if strings.HasSuffix(sourcePath, ".go") {
-				seg.CodeForJs = strings.Trim(seg.Code, "\n") + "\n"
-			}
+// nameHighlights marks the lines of named highlight groups in chroma's
+// output with data-hl, and gives the first line of each group the id that
+// docs link to.
+func nameHighlights(rendered string, highlights []Highlight) string {
+	names := make(map[int]string)
+	for _, hl := range highlights {
+		if hl.Name != "" {
+			names[hl.Line] = hl.Name
 		}
 	}
-	// we are only interested in the 'go' code to pass to play.golang.org
-	if lexer != "go" {
-		filecontent = ""
+	if len(names) == 0 {
+		return rendered
 	}
-	return segs, filecontent
+	line := 0
+	seen := make(map[string]bool)
+	return chromaLinePat.ReplaceAllStringFunc(rendered, func(span string) string {
+		line++
+		name, ok := names[line]
+		if !ok {
+			return span
+		}
+		attrs := fmt.Sprintf(` data-hl="%s"`, name)
+		if !seen[name] {
+			attrs = fmt.Sprintf(` id="hl-%s"`, name) + attrs
+			seen[name] = true
+		}
+		return span[:len(span)-1] + attrs + ">"
+	})
 }
 
-func parseExamples() []*Example {
-	var exampleNames []string
-	for _, line := range readLines("examples.txt") {
-		if line != "" && !strings.HasPrefix(line, "#") {
-			exampleNames = append(exampleNames, line)
+// retokeniseLock is held exclusively while tokenising again. chroma gives
+// each regexp match a 250ms deadline and quietly emits Error tokens when it
+// is missed, which can happen when many examples are lexed in parallel on a
+// busy machine. Retrying with nothing else running keeps the output the same
+// as a serial run.
+var retokeniseLock sync.RWMutex
+
+func tokenise(lexer chroma.Lexer, code string) []chroma.Token {
+	retokeniseLock.RLock()
+	tokens, err := chroma.Tokenise(lexer, nil, code)
+	retokeniseLock.RUnlock()
+	check(err)
+	hasError := func(t chroma.Token) bool { return t.Type == chroma.Error }
+	if *jobs > 1 && slices.ContainsFunc(tokens, hasError) {
+		retokeniseLock.Lock()
+		defer retokeniseLock.Unlock()
+		tokens, err = chroma.Tokenise(lexer, nil, code)
+		check(err)
+	}
+	return tokens
+}
+
+// stdlibSymbols maps the import paths of standard library packages to the
+// anchors of their exported symbols on pkg.go.dev, like "WaitGroup" and
+// "WaitGroup.Add". Packages are loaded from GOROOT on first use; a nil entry
+// means there is no such package.
+var stdlibSymbols = struct {
+	sync.Mutex
+	pkgs map[string]map[string]bool
+}{pkgs: make(map[string]map[string]bool)}
+
+func packageSymbols(path string) map[string]bool {
+	stdlibSymbols.Lock()
+	defer stdlibSymbols.Unlock()
+	if symbols, ok := stdlibSymbols.pkgs[path]; ok {
+		return symbols
+	}
+	symbols := loadPackageSymbols(path)
+	stdlibSymbols.pkgs[path] = symbols
+	return symbols
+}
+
+func loadPackageSymbols(path string) map[string]bool {
+	if strings.Contains(path, "internal") || strings.Contains(path, "vendor") || strings.Contains(path, ".") {
+		return nil
+	}
+	bp, err := build.Default.ImportDir(filepath.Join(build.Default.GOROOT, "src", path), 0)
+	if err != nil || !bp.Goroot && bp.Name == "" {
+		return nil
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(bp.Dir, name), nil, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			return nil
 		}
+		files = append(files, f)
 	}
-	examples := make([]*Example, 0)
-	for i, exampleName := range exampleNames {
-		if verbose() {
-			fmt.Printf("Processing %s [%d/%d]\n", exampleName, i+1, len(exampleNames))
-		}
-		example := Example{Name: exampleName}
-		exampleID := strings.ToLower(exampleName)
-		exampleID = strings.Replace(exampleID, " ", "-", -1)
-		exampleID = strings.Replace(exampleID, "/", "-", -1)
-		exampleID = strings.Replace(exampleID, "'", "", -1)
-		exampleID = dashPat.ReplaceAllString(exampleID, "-")
-		example.ID = exampleID
-		example.Segs = make([][]*Seg, 0)
-		sourcePaths := mustGlob("examples/" + exampleID + "/*")
-		for _, sourcePath := range sourcePaths {
-			if !isDir(sourcePath) {
-				if strings.HasSuffix(sourcePath, ".hash") {
-					example.GoCodeHash, example.URLHash = parseHashFile(sourcePath)
-				} else {
-					sourceSegs, filecontents := parseAndRenderSegs(sourcePath)
-					if filecontents != "" {
-						example.GoCode = filecontents
+	pkg, err := doc.NewFromFiles(fset, files, path)
+	if err != nil {
+		return nil
+	}
+	symbols := make(map[string]bool)
+	addValues := func(values []*doc.Value) {
+		for _, v := range values {
+			for _, name := range v.Names {
+				symbols[name] = true
+			}
+		}
+	}
+	addValues(pkg.Consts)
+	addValues(pkg.Vars)
+	for _, f := range pkg.Funcs {
+		symbols[f.Name] = true
+	}
+	for _, t := range pkg.Types {
+		symbols[t.Name] = true
+		addValues(t.Consts)
+		addValues(t.Vars)
+		for _, f := range t.Funcs {
+			symbols[f.Name] = true
+		}
+		for _, m := range t.Methods {
+			symbols[t.Name+"."+m.Name] = true
+		}
+		// Fields of structs and methods of interfaces have anchors too.
+		for _, spec := range t.Decl.Specs {
+			var fields *ast.FieldList
+			switch typ := spec.(*ast.TypeSpec).Type.(type) {
+			case *ast.StructType:
+				fields = typ.Fields
+			case *ast.InterfaceType:
+				fields = typ.Methods
+			}
+			if fields == nil {
+				continue
+			}
+			for _, field := range fields.List {
+				for _, name := range field.Names {
+					if name.IsExported() {
+						symbols[t.Name+"."+name.Name] = true
 					}
-					example.Segs = append(example.Segs, sourceSegs)
 				}
 			}
 		}
-		newCodeHash := sha1Sum(example.GoCode)
-		if example.GoCodeHash != newCodeHash {
-			example.URLHash = resetURLHashFile(newCodeHash, example.GoCode, "examples/"+example.ID+"/"+example.ID+".hash")
-		}
-		examples = append(examples, &example)
 	}
-	for i, example := range examples {
-		if i > 0 {
-			example.PrevExample = examples[i-1]
+	return symbols
+}
+
+// sourceImports maps the names a Go source file imports packages under to
+// their paths, for resolving package names in its docs.
+func sourceImports(sourcePath, src string) map[string]string {
+	imports := make(map[string]string)
+	if !strings.HasSuffix(sourcePath, ".go") {
+		return imports
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), sourcePath, src, parser.ImportsOnly)
+	if err != nil {
+		return imports
+	}
+	for _, spec := range f.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
 		}
-		if i < (len(examples) - 1) {
-			example.NextExample = examples[i+1]
+		name := path[strings.LastIndex(path, "/")+1:]
+		if spec.Name != nil {
+			name = spec.Name.Name
+		} else if strings.HasPrefix(name, "v") && strings.Contains(path, "/") {
+			// math/rand/v2 is imported as rand.
+			if _, err := strconv.Atoi(name[1:]); err == nil {
+				dir := path[:strings.LastIndex(path, "/")]
+				name = dir[strings.LastIndex(dir, "/")+1:]
+			}
 		}
+		imports[name] = path
 	}
-	return examples
+	return imports
 }
 
-func renderIndex(examples []*Example) {
-	if verbose() {
-		fmt.Println("Rendering index")
+// docLinkPat matches Go doc links like [sync.WaitGroup], [*bytes.Buffer]
+// and [encoding/json.Decoder.Decode], but not markdown links.
+var docLinkPat = regexp.MustCompile(`(^|[^\]\w])\[(\*?)((?:[a-z][a-z0-9]*/)*[a-z][a-z0-9]*)\.([A-Za-z_]\w*(?:\.[A-Za-z_]\w*)?)\]([^(\[:]|$)`)
+
+// codeRefPat matches backticked qualified identifiers like `errors.As` or
+// `time.Tick()`, unless they are the text of a link already.
+var codeRefPat = regexp.MustCompile("(^|[^\\[])`(\\*?)((?:[a-z][a-z0-9]*/)*[a-z][a-z0-9]*)\\.([A-Z]\\w*(?:\\.[A-Z]\\w*)?)(\\(\\))?`([^\\]]|$)")
+
+// linkDocs links references to standard library APIs in docs markdown to
+// their documentation on pkg.go.dev: Go doc links like [sync.WaitGroup], and
+// backticked qualified identifiers like `sync.WaitGroup`. Packages are
+// resolved through the imports of the source file, falling back to
+// standard library paths. References to packages that resolve but symbols
+// that don't exist in them are returned as unknown and left unlinked, as are
+// doc links to unknown packages.
+func linkDocs(docs string, imports map[string]string) (string, []string) {
+	var unknown []string
+	resolve := func(pkg, name string) (string, bool) {
+		path := pkg
+		if p, ok := imports[pkg]; ok {
+			path = p
+		}
+		symbols := packageSymbols(path)
+		if symbols == nil {
+			return "", false
+		}
+		if !symbols[name] {
+			unknown = append(unknown, pkg+"."+name)
+			return "", false
+		}
+		return "https://pkg.go.dev/" + path + "#" + name, true
 	}
-	indexTmpl := template.New("index")
-	template.Must(indexTmpl.Parse(mustReadFile("templates/footer.tmpl")))
-	template.Must(indexTmpl.Parse(mustReadFile("templates/index.tmpl")))
-	indexF, err := os.Create(siteDir + "/index.html")
-	check(err)
-	defer indexF.Close()
-	check(indexTmpl.Execute(indexF, examples))
+	docs = replaceAllSubmatchFunc(docLinkPat, docs, func(m []string) string {
+		url, ok := resolve(m[3], m[4])
+		if !ok {
+			if packageSymbols(m[3]) == nil && imports[m[3]] == "" {
+				unknown = append(unknown, m[3]+"."+m[4])
+			}
+			return m[0]
+		}
+		return fmt.Sprintf("%s[%s%s.%s](%s)%s", m[1], m[2], m[3], m[4], url, m[5])
+	})
+	docs = replaceAllSubmatchFunc(codeRefPat, docs, func(m []string) string {
+		url, ok := resolve(m[3], m[4])
+		if !ok {
+			return m[0]
+		}
+		return fmt.Sprintf("%s[`%s%s.%s%s`](%s)%s", m[1], m[2], m[3], m[4], m[5], url, m[6])
+	})
+	return docs, unknown
 }
 
-func renderExamples(examples []*Example) {
-	if verbose() {
-		fmt.Println("Rendering examples")
-	}
-	exampleTmpl := template.New("example")
-	template.Must(exampleTmpl.Parse(mustReadFile("templates/footer.tmpl")))
-	template.Must(exampleTmpl.Parse(mustReadFile("templates/example.tmpl")))
-	for _, example := range examples {
-		exampleF, err := os.Create(siteDir + "/" + example.ID)
-		check(err)
-		defer exampleF.Close()
-		check(exampleTmpl.Execute(exampleF, example))
+// replaceAllSubmatchFunc is like Regexp.ReplaceAllStringFunc, but passes the
+// submatches to repl. Matches are found again after each replacement, so
+// that adjacent references sharing a delimiter are all replaced.
+func replaceAllSubmatchFunc(re *regexp.Regexp, s string, repl func([]string) string) string {
+	var buf strings.Builder
+	for {
+		loc := re.FindStringSubmatchIndex(s)
+		if loc == nil {
+			buf.WriteString(s)
+			return buf.String()
+		}
+		m := make([]string, len(loc)/2)
+		for i := range m {
+			if loc[2*i] >= 0 {
+				m[i] = s[loc[2*i]:loc[2*i+1]]
+			}
+		}
+		// The last group is the delimiter after the match, which may start
+		// the next one.
+		last := m[len(m)-1]
+		buf.WriteString(s[:loc[0]])
+		r := repl(m)
+		buf.WriteString(r[:len(r)-len(last)])
+		s = s[loc[1]-len(last):]
 	}
 }
 
-func render404() {
-	if verbose() {
-		fmt.Println("Rendering 404")
-	}
-	tmpl := template.New("404")
-	template.Must(tmpl.Parse(mustReadFile("templates/footer.tmpl")))
-	template.Must(tmpl.Parse(mustReadFile("templates/404.tmpl")))
-	file, err := os.Create(siteDir + "/404.html")
-	check(err)
-	defer file.Close()
-	check(tmpl.Execute(file, ""))
+// renderedSource is what the render cache keeps for each source file.
+type renderedSource struct {
+	Segs        []*Seg
+	FileContent string
+	// Warnings are reported on every run, even when the rendering is
+	// cached.
+	Warnings []string
 }
 
-func main() {
-	if len(os.Args) > 1 {
-		siteDir = os.Args[1]
+func parseAndRenderSegs(sourcePath string) ([]*Seg, string, []string) {
+	key := cache.key("segs", sourcePath, mustReadFile(sourcePath))
+	var cached renderedSource
+	if cache.getJSON(key, &cached) {
+		debug("CACHED: " + sourcePath)
+		return cached.Segs, cached.FileContent, cached.Warnings
 	}
-	ensureDir(siteDir)
+	segs, filecontent := parseSegs(sourcePath)
+	lexer := fileTypeOf(sourcePath).Name
+	imports := sourceImports(sourcePath, filecontent)
+	var warnings []string
+	for _, seg := range segs {
+		seg.Lexer = lexer
+		if seg.Docs != "" {
+			var unknown []string
+			seg.Docs, unknown = linkDocs(seg.Docs, imports)
+			for _, symbol := range unknown {
+				p := problem{sourcePath, lineContaining(filecontent, symbol), "warning: unknown symbol " + symbol}
+				warnings = append(warnings, p.String())
+			}
+			seg.DocsRendered = markdown(seg.Docs)
+		}
+		if seg.Code != "" {
+			seg.CodeRendered = chromaFormat(seg.Code, sourcePath, seg.Highlights...)
 
-	copyFile("templates/site.css", siteDir+"/site.css")
-	copyFile("templates/site.js", siteDir+"/site.js")
-	copyFile("templates/favicon.ico", siteDir+"/favicon.ico")
-	copyFile("templates/play.png", siteDir+"/play.png")
-	copyFile("templates/clipboard.png", siteDir+"/clipboard.png")
-	examples := parseExamples()
-	renderIndex(examples)
-	renderExamples(examples)
+			// adding the content to the js code for copying to the clipboard
+			if strings.HasSuffix(sourcePath, ".go") && !seg.Setup {
+				seg.CodeForJs = strings.Trim(seg.Code, "\n") + "\n"
+			}
+		}
+	}
+	cache.putJSON(key, renderedSource{segs, filecontent, warnings})
+	return segs, filecontent, warnings
+}
+
+var hlLinkPat = regexp.MustCompile(`\]\(#hl-([\w-]+)\)`)
+
+// checkHighlights makes sure that every highlight group docs link to exists,
+// and that each group is within one code segment, so that its id is unique
+// on the page.
+func checkHighlights(example *Example, dir string) {
+	groups := make(map[string]*Seg)
+	for i, segs := range example.Segs {
+		path := dir + "/" + example.Files[i]
+		for _, seg := range segs {
+			for _, hl := range seg.Highlights {
+				if hl.Name == "" {
+					continue
+				}
+				if other, ok := groups[hl.Name]; ok && other != seg {
+					example.problemf(path, lineContaining(mustReadFile(path), "HL:"+hl.Name), "highlight group %q spans more than one code segment", hl.Name)
+				}
+				groups[hl.Name] = seg
+			}
+		}
+	}
+	for i, segs := range example.Segs {
+		path := dir + "/" + example.Files[i]
+		for _, seg := range segs {
+			for _, m := range hlLinkPat.FindAllStringSubmatch(seg.Docs, -1) {
+				if groups[m[1]] == nil {
+					example.problemf(path, lineContaining(mustReadFile(path), m[0]), "link to unknown highlight group %q", m[1])
+				}
+			}
+		}
+	}
+}
+
+// requirement is the Go version an example needs, and the use of a language
+// feature or standard library API that needs it.
+type requirement struct {
+	Version, Reason string
+	Path            string
+	Line, Column    int
+}
+
+func (r requirement) before(other requirement) bool {
+	if r.Path != other.Path {
+		return r.Path < other.Path
+	}
+	if r.Line != other.Line {
+		return r.Line < other.Line
+	}
+	return r.Column < other.Column
+}
+
+// builtinVersions are the Go versions that added predeclared identifiers.
+var builtinVersions = map[string]string{
+	"any": "go1.18", "comparable": "go1.18",
+	"clear": "go1.21", "min": "go1.21", "max": "go1.21",
+}
+
+var apiLinePat = regexp.MustCompile(`^pkg ([\w/.-]+)(?: \([^)]*\))?, (?:(?:func|const|var) (\w+)|type (\w+)(?:\[[^\]]*\])? (?:struct|interface), (\w+)|type (\w+)|method \(\*?(\w+)(?:\[[^\]]*\])?\) (\w+))`)
+
+// apiVersions maps standard library packages and their symbols, like
+// "slices", "slices.Sort" and "sync.WaitGroup.Go", to the Go version that
+// added them. They are read from the API files of the installed Go.
+var apiVersions = sync.OnceValue(func() map[string]string {
+	versions := make(map[string]string)
+	add := func(key, v string) {
+		if old, ok := versions[key]; !ok || version.Compare(v, old) < 0 {
+			versions[key] = v
+		}
+	}
+	for _, path := range mustGlob(filepath.Join(build.Default.GOROOT, "api", "go1*.txt")) {
+		v := strings.TrimSuffix(filepath.Base(path), ".txt")
+		for _, line := range readLines(path) {
+			m := apiLinePat.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			add(m[1], v)
+			names := slices.DeleteFunc(m[2:], func(s string) bool { return s == "" })
+			add(m[1]+"."+strings.Join(names, "."), v)
+		}
+	}
+	return versions
+})
+
+// apiKey returns the key of a package-level object or method in
+// apiVersions, or "" for other objects.
+func apiKey(obj types.Object) string {
+	if obj.Pkg() == nil {
+		return ""
+	}
+	if fn, ok := obj.(*types.Func); ok && fn.Signature().Recv() != nil {
+		return typeKey(fn.Signature().Recv().Type(), obj)
+	}
+	if obj.Parent() != obj.Pkg().Scope() {
+		return ""
+	}
+	return obj.Pkg().Path() + "." + obj.Name()
+}
+
+// typeKey returns the key in apiVersions of a field or method of t.
+func typeKey(t types.Type, obj types.Object) string {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || obj.Pkg() == nil {
+		return ""
+	}
+	return obj.Pkg().Path() + "." + named.Obj().Name() + "." + obj.Name()
+}
+
+// lockedImporter lets examples that are parsed at the same time share an
+// importer, and with it the packages it has loaded.
+type lockedImporter struct {
+	sync.Mutex
+	imp types.Importer
+}
+
+func (l *lockedImporter) Import(path string) (*types.Package, error) {
+	l.Lock()
+	defer l.Unlock()
+	return l.imp.Import(path)
+}
+
+var goImporter = &lockedImporter{imp: importer.Default()}
+
+// goRequirement works out the oldest Go version that can build the Go files
+// of an example, from the language features and the standard library APIs
+// they use. The files of each directory are type checked as a package; type
+// errors are left to go vet.
+func goRequirement(dir string, files []string) requirement {
+	var sources []string
+	for _, file := range files {
+		sources = append(sources, file, mustReadFile(dir+"/"+file))
+	}
+	key := cache.key(append([]string{"go-version"}, sources...)...)
+	req := requirement{Version: "go1"}
+	if cache.getJSON(key, &req) {
+		return req
+	}
+	// The earliest use of the newest version is the reason.
+	need := func(fset *token.FileSet, v, reason string, pos token.Pos) {
+		p := fset.Position(pos)
+		at := requirement{v, reason, p.Filename, p.Line, p.Column}
+		if c := version.Compare(v, req.Version); c > 0 || c == 0 && (req.Path == "" || at.before(req)) {
+			req = at
+		}
+	}
+	fset := token.NewFileSet()
+	pkgs := make(map[string][]*ast.File)
+	var dirs []string
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, dir+"/"+file, nil, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		pkg := filepath.Dir(file) + " " + f.Name.Name
+		if pkgs[pkg] == nil {
+			dirs = append(dirs, pkg)
+		}
+		pkgs[pkg] = append(pkgs[pkg], f)
+	}
+	for _, pkg := range dirs {
+		info := &types.Info{
+			Types:      make(map[ast.Expr]types.TypeAndValue),
+			Uses:       make(map[*ast.Ident]types.Object),
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		}
+		conf := types.Config{Importer: goImporter, Error: func(error) {}}
+		conf.Check(pkg, fset, pkgs[pkg], info)
+		for _, f := range pkgs[pkg] {
+			for _, spec := range f.Imports {
+				path, _ := strconv.Unquote(spec.Path.Value)
+				if v, ok := apiVersions()[path]; ok {
+					need(fset, v, "package "+path, spec.Pos())
+				}
+			}
+			ast.Inspect(f, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.FuncType:
+					if n.TypeParams != nil {
+						need(fset, "go1.18", "type parameters", n.TypeParams.Pos())
+					}
+				case *ast.TypeSpec:
+					if n.TypeParams != nil {
+						need(fset, "go1.18", "type parameters", n.TypeParams.Pos())
+					}
+				case *ast.RangeStmt:
+					if t := info.TypeOf(n.X); t != nil {
+						switch t := t.Underlying().(type) {
+						case *types.Basic:
+							if t.Info()&types.IsInteger != 0 {
+								need(fset, "go1.22", "range over int", n.Pos())
+							}
+						case *types.Signature:
+							need(fset, "go1.23", "range over function", n.Pos())
+						}
+					}
+				case *ast.BasicLit:
+					lit := strings.ToLower(n.Value)
+					if n.Kind == token.INT && (strings.HasPrefix(lit, "0b") || strings.HasPrefix(lit, "0o") || strings.Contains(lit, "_")) {
+						need(fset, "go1.13", "number literal "+n.Value, n.Pos())
+					}
+				}
+				return true
+			})
+		}
+		for id, obj := range info.Uses {
+			if obj.Pkg() == nil {
+				if v, ok := builtinVersions[obj.Name()]; ok {
+					need(fset, v, obj.Name(), id.Pos())
+				}
+			} else if v, ok := apiVersions()[apiKey(obj)]; ok {
+				need(fset, v, apiKey(obj), id.Pos())
+			}
+		}
+		for sel, s := range info.Selections {
+			if s.Kind() != types.FieldVal {
+				continue
+			}
+			if v, ok := apiVersions()[typeKey(s.Recv(), s.Obj())]; ok {
+				need(fset, v, typeKey(s.Recv(), s.Obj()), sel.Sel.Pos())
+			}
+		}
+	}
+	cache.putJSON(key, req)
+	return req
+}
+
+var goDirectivePat = regexp.MustCompile(`(?m)^go (\d+\.\d+(?:\.\d+)?)\s*$`)
+
+// goModVersion returns the language version of the module's go directive,
+// like "go1.25", or "" if there is none.
+var goModVersion = sync.OnceValue(func() string {
+	m := goDirectivePat.FindStringSubmatch(mustReadFile("go.mod"))
+	if m == nil {
+		return ""
+	}
+	return version.Lang("go" + m[1])
+})
+
+// exampleMeta is an example's optional meta.json. Everything but Files can
+// also be given as front matter.
+type exampleMeta struct {
+	// Files lists source files in the order they are shown. Files that
+	// aren't listed follow in the order of their paths.
+	Files         []string `json:"files"`
+	Tags          []string `json:"tags"`
+	Difficulty    string   `json:"difficulty"`
+	Summary       string   `json:"summary"`
+	Related       []string `json:"related"`
+	Prerequisites []string `json:"prerequisites"`
+
+	// pos says where each key was set.
+	pos map[string]metaPos
+}
+
+// metaPos is the file and line of a front matter key.
+type metaPos struct {
+	path string
+	line int
+}
+
+var difficulties = []string{"beginner", "intermediate", "advanced"}
+
+func readExampleMeta(example *Example, dir string) exampleMeta {
+	meta := exampleMeta{pos: make(map[string]metaPos)}
+	path := dir + "/meta.json"
+	dat, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return meta
+	}
+	check(err)
+	dec := json.NewDecoder(bytes.NewReader(dat))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&meta); err != nil {
+		example.problemf(path, 0, "%v", err)
+	}
+	for _, key := range []string{"tags", "difficulty", "summary", "related", "prerequisites"} {
+		if line := lineContaining(string(dat), `"`+key+`"`); line > 0 {
+			meta.pos[key] = metaPos{path, line}
+		}
+	}
+	return meta
+}
+
+// frontMatter returns the "key: value" lines of the front matter a source
+// file starts with, a block of comment lines between two "// ---" lines, and
+// the number of lines it takes up, blank lines after it included.
+func frontMatter(ft *fileType, lines []string) ([]string, int) {
+	fence := ft.Comment + " ---"
+	if ft.Comment == "" || len(lines) == 0 || strings.TrimSpace(lines[0]) != fence {
+		return nil, 0
+	}
+	var body []string
+	for i := 1; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == fence {
+			n := i + 1
+			for n < len(lines) && strings.TrimSpace(lines[n]) == "" {
+				n++
+			}
+			return body, n
+		}
+		if !strings.HasPrefix(line, ft.Comment) {
+			break
+		}
+		body = append(body, strings.TrimSpace(strings.TrimPrefix(line, ft.Comment)))
+	}
+	return nil, 0
+}
+
+// readFrontMatter sets the fields of meta that the front matter of the
+// source file at path gives. Lists are separated by commas.
+func readFrontMatter(example *Example, path string, meta *exampleMeta) {
+	body, _ := frontMatter(fileTypeOf(path), readLines(path))
+	if meta.pos == nil {
+		meta.pos = make(map[string]metaPos)
+	}
+	for i, line := range body {
+		key, value, _ := strings.Cut(line, ":")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		var field any
+		switch key {
+		case "tags":
+			field = &meta.Tags
+		case "difficulty":
+			field = &meta.Difficulty
+		case "summary":
+			field = &meta.Summary
+		case "related":
+			field = &meta.Related
+		case "prerequisites":
+			field = &meta.Prerequisites
+		default:
+			example.problemf(path, i+2, "unknown front matter key %q", key)
+			continue
+		}
+		meta.pos[key] = metaPos{path, i + 2}
+		switch field := field.(type) {
+		case *string:
+			if *field != "" {
+				example.problemf(path, i+2, "%s is set more than once", key)
+			}
+			*field = value
+		case *[]string:
+			if *field != nil {
+				example.problemf(path, i+2, "%s is set more than once", key)
+			}
+			*field = []string{}
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					*field = append(*field, item)
+				}
+			}
+		}
+	}
+}
+
+// fileRank orders the files of an example unless its meta.json says
+// otherwise: the program, its tests, its module, data files and then the
+// transcripts of running it.
+func fileRank(file string) int {
+	switch {
+	case strings.HasSuffix(file, "_test.go"):
+		return 1
+	case strings.HasSuffix(file, ".go"):
+		return 0
+	case filepath.Base(file) == "go.mod":
+		return 2
+	case strings.HasSuffix(file, ".sh"):
+		return 4
+	}
+	return 3
+}
+
+// orderFiles puts the files of an example in the order its meta.json asks
+// for.
+func orderFiles(example *Example, dir string, files []string, meta exampleMeta) []string {
+	var ordered []string
+	for _, file := range meta.Files {
+		if !slices.Contains(files, file) {
+			example.problemf(dir+"/meta.json", 0, "no source file %s", file)
+			continue
+		}
+		if !slices.Contains(ordered, file) {
+			ordered = append(ordered, file)
+		}
+	}
+	for _, file := range files {
+		if !slices.Contains(ordered, file) {
+			ordered = append(ordered, file)
+		}
+	}
+	return ordered
+}
+
+// playgroundSource returns the code to share on the playground: a single
+// file as is, several files as a txtar archive, which the playground
+// unpacks.
+func playgroundSource(files, sources []string) string {
+	if len(files) == 1 {
+		return sources[0]
+	}
+	var buf strings.Builder
+	for i, file := range files {
+		fmt.Fprintf(&buf, "-- %s --\n%s", file, sources[i])
+		if !strings.HasSuffix(sources[i], "\n") {
+			buf.WriteString("\n")
+		}
+	}
+	return buf.String()
+}
+
+// lineContaining returns the number of the first line of src containing s,
+// or 0.
+func lineContaining(src, s string) int {
+	i := strings.Index(src, s)
+	if i < 0 {
+		return 0
+	}
+	return strings.Count(src[:i], "\n") + 1
+}
+
+// readExampleNames returns the example names listed in examples.txt, along
+// with the line each one is on and the section it's in. A "## Title" line
+// starts a section; other lines starting with "#" are comments.
+func readExampleNames() ([]string, []int, []string) {
+	var exampleNames []string
+	var exampleLines []int
+	var exampleSections []string
+	section := ""
+	for i, line := range readLines("examples.txt") {
+		if title, ok := strings.CutPrefix(line, "## "); ok {
+			section = strings.TrimSpace(title)
+		} else if line != "" && !strings.HasPrefix(line, "#") {
+			exampleNames = append(exampleNames, line)
+			exampleLines = append(exampleLines, i+1)
+			exampleSections = append(exampleSections, section)
+		}
+	}
+	return exampleNames, exampleLines, exampleSections
+}
+
+// groupSections groups consecutive examples of the same section, for the
+// index page.
+func groupSections(examples []*Example) []*Section {
+	var sections []*Section
+	for _, example := range examples {
+		if len(sections) == 0 || sections[len(sections)-1].Title != example.Section {
+			sections = append(sections, &Section{ID: example.SectionID, Title: example.Section})
+		}
+		last := sections[len(sections)-1]
+		last.Examples = append(last.Examples, example)
+	}
+	return sections
+}
+
+func exampleID(exampleName string) string {
+	id := strings.ToLower(exampleName)
+	id = strings.Replace(id, " ", "-", -1)
+	id = strings.Replace(id, "/", "-", -1)
+	id = strings.Replace(id, "'", "", -1)
+	return dashPat.ReplaceAllString(id, "-")
+}
+
+func parseExamples(backend shareBackend) []*Example {
+	exampleNames, exampleLines, exampleSections := readExampleNames()
+	examples := make([]*Example, len(exampleNames))
+	forEach(len(exampleNames), func(i int) {
+		if verbose() {
+			fmt.Printf("Processing %s [%d/%d]\n", exampleNames[i], i+1, len(exampleNames))
+		}
+		examples[i] = parseExample(exampleNames[i], exampleLines[i], backend)
+		examples[i].Section = exampleSections[i]
+		if exampleSections[i] != "" {
+			examples[i].SectionID = exampleID(exampleSections[i])
+		}
+	})
+	seen := make(map[string]bool)
+	ids := make(map[string]bool)
+	for _, example := range examples {
+		if seen[example.ID] {
+			example.problemf("examples.txt", example.line, "duplicate example %s", example.ID)
+		}
+		seen[example.ID] = true
+		ids[example.ID] = true
+	}
+	for _, example := range examples {
+		for _, note := range example.Notes {
+			if seen[note.ID] {
+				example.problemf("examples/"+example.ID, 0, "note page %s clashes with another page", note.ID)
+			}
+			seen[note.ID] = true
+		}
+	}
+	tags := make(map[string]bool)
+	for _, example := range examples {
+		for _, tag := range example.Tags {
+			if seen[tag.ID] && !tags[tag.ID] {
+				example.metaProblemf("tags", "page of tag %q clashes with page %s", tag.Name, tag.ID)
+			}
+			tags[tag.ID] = true
+		}
+	}
+	for _, example := range examples {
+		for _, id := range example.Related {
+			if !ids[id] {
+				example.metaProblemf("related", "unknown related example %q", id)
+			}
+		}
+		for _, id := range example.Prerequisites {
+			if !ids[id] {
+				example.metaProblemf("prerequisites", "unknown prerequisite %q", id)
+			}
+		}
+	}
+	return examples
+}
+
+// reportProblems prints the problems of all examples to w, in examples.txt
+// order, and returns the examples that have none.
+func reportProblems(w io.Writer, examples []*Example) ([]*Example, bool) {
+	var valid []*Example
+	count := 0
+	for _, example := range examples {
+		for _, warning := range example.warnings {
+			fmt.Fprintln(w, warning)
+		}
+		for _, p := range example.problems {
+			fmt.Fprintln(w, p)
+			count++
+		}
+		if len(example.problems) == 0 {
+			valid = append(valid, example)
+		}
+	}
+	if count > 0 {
+		fmt.Fprintf(w, "%d problem(s) in %d example(s)\n", count, len(examples)-len(valid))
+	}
+	return valid, count == 0
+}
+
+// linkExamples sets up the prev/next chain between examples, and the links
+// to their related examples and prerequisites. Links to examples that
+// weren't rendered are left out.
+func linkExamples(examples []*Example) {
+	byID := make(map[string]*Example)
+	for _, example := range examples {
+		byID[example.ID] = example
+	}
+	links := func(ids []string) []pageLink {
+		var links []pageLink
+		for _, id := range ids {
+			if other, ok := byID[id]; ok {
+				links = append(links, pageLink{other.ID, other.Name})
+			}
+		}
+		return links
+	}
+	for i, example := range examples {
+		if i > 0 {
+			example.PrevExample = examples[i-1]
+		}
+		if i < (len(examples) - 1) {
+			example.NextExample = examples[i+1]
+		}
+		example.RelatedLinks = links(example.Related)
+		example.PrerequisiteLinks = links(example.Prerequisites)
+	}
+}
+
+// pageIDs returns the IDs of the pages the site has for the examples: their
+// own, their notes', their tags' and the one with all examples.
+func pageIDs(examples []*Example) map[string]bool {
+	ids := map[string]bool{"all.html": true}
+	for _, example := range examples {
+		ids[example.ID] = true
+		for _, note := range example.Notes {
+			ids[note.ID] = true
+		}
+		for _, tag := range example.Tags {
+			ids[tag.ID] = true
+		}
+	}
+	return ids
+}
+
+// checkDocLinks checks the relative links in the docs of the examples, as
+// parseSegs extracts them, and in their notes. They have to name a page of
+// the site, or from a note, another note of the example. Links to other
+// sites are left to tools/checklinks, which finds them in the generated site.
+func checkDocLinks(examples []*Example) []problem {
+	ids := pageIDs(examples)
+	var problems []problem
+	for _, example := range examples {
+		for i, file := range example.Files {
+			path := "examples/" + example.ID + "/" + file
+			src, from := mustReadFile(path), 0
+			for _, seg := range example.Segs[i] {
+				problems = append(problems, docLinkProblems(path, src, &from, seg.Docs, ids)...)
+			}
+		}
+		for _, note := range example.Notes {
+			src, from := mustReadFile(note.path), 0
+			problems = append(problems, docLinkProblems(note.path, src, &from, src, ids)...)
+		}
+	}
+	return problems
+}
+
+var docsLinkPat = regexp.MustCompile(`\]\(([^)\s]+)\)`)
+var inlineCodePat = regexp.MustCompile("`[^`]*`")
+
+// docLinkProblems checks the links in the markdown docs, which come from the
+// file at path with the contents src, and reports them at the line of src
+// they're on. Links are looked for in src from offset *from on, and *from
+// moves past each one found, so that docs must be checked in order. Code is
+// skipped.
+func docLinkProblems(path, src string, from *int, docs string, ids map[string]bool) []problem {
+	var problems []problem
+	fenced := false
+	for _, line := range strings.Split(docs, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fenced = !fenced
+			continue
+		}
+		if fenced {
+			continue
+		}
+		for _, m := range docsLinkPat.FindAllStringSubmatch(inlineCodePat.ReplaceAllString(line, ""), -1) {
+			target := m[1]
+			at := 0
+			if i := strings.Index(src[*from:], m[0]); i >= 0 {
+				*from += i + len(m[0])
+				at = strings.Count(src[:*from], "\n") + 1
+			}
+			u, err := url.Parse(target)
+			switch {
+			case err != nil:
+				problems = append(problems, problem{path, at, fmt.Sprintf("malformed link %q", target)})
+			case u.Scheme != "" || u.Path == "":
+			case strings.HasSuffix(path, ".md") && strings.HasSuffix(u.Path, ".md"):
+				if _, err := os.Stat(filepath.Join(filepath.Dir(path), u.Path)); err != nil {
+					problems = append(problems, problem{path, at, fmt.Sprintf("link to missing note %q", u.Path)})
+				}
+			case !ids[u.Path]:
+				problems = append(problems, problem{path, at, fmt.Sprintf("link to unknown example %q", u.Path)})
+			}
+		}
+	}
+	return problems
+}
+
+func parseExample(exampleName string, line int, backend shareBackend) *Example {
+	example := Example{Name: exampleName, line: line}
+	exampleID := exampleID(exampleName)
+	example.ID = exampleID
+	example.Segs = make([][]*Seg, 0)
+	if !isDir("examples/" + exampleID) {
+		example.problemf("examples.txt", line, "no example directory examples/%s", exampleID)
+		return &example
+	}
+	dir := "examples/" + exampleID
+	var notePaths, files []string
+	err := filepath.WalkDir(dir, func(sourcePath string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, sourcePath)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "meta.json" {
+			return nil
+		}
+		if strings.HasSuffix(rel, ".md") {
+			notePaths = append(notePaths, sourcePath)
+		} else if strings.HasSuffix(rel, ".hash") && !strings.Contains(rel, "/") {
+			var ok bool
+			example.GoCodeHash, example.URLHash, ok = parseHashFile(sourcePath)
+			if !ok {
+				example.problemf(sourcePath, 1, "malformed hash file, expected a code hash and a URL hash line")
+			}
+		} else if fileTypeOf(sourcePath) == nil {
+			example.problemf(sourcePath, 0, "unsupported file type")
+		} else {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	check(err)
+	slices.SortStableFunc(files, func(a, b string) int {
+		return fileRank(a) - fileRank(b)
+	})
+	meta := readExampleMeta(&example, dir)
+	files = orderFiles(&example, dir, files, meta)
+	if len(files) > 0 {
+		readFrontMatter(&example, dir+"/"+files[0], &meta)
+	}
+	example.metaPos = meta.pos
+	for _, tag := range meta.Tags {
+		if tagID(tag) == tagID("") {
+			example.metaProblemf("tags", "tag %q has no letters or digits", tag)
+		}
+		example.Tags = append(example.Tags, pageLink{tagID(tag), tag})
+	}
+	if meta.Difficulty != "" && !slices.Contains(difficulties, meta.Difficulty) {
+		example.metaProblemf("difficulty", "unknown difficulty %q, want one of %s", meta.Difficulty, strings.Join(difficulties, ", "))
+	}
+	example.Difficulty, example.Summary = meta.Difficulty, meta.Summary
+	example.Related, example.Prerequisites = meta.Related, meta.Prerequisites
+
+	// The playground gets every file but the transcripts, in txtar format
+	// when there is more than one.
+	var playFiles []string
+	var playSources []string
+	for _, file := range files {
+		sourceSegs, filecontents, warnings := parseAndRenderSegs(dir + "/" + file)
+		example.warnings = append(example.warnings, warnings...)
+		if fileTypeOf(file).Name != "console" {
+			playFiles = append(playFiles, file)
+			playSources = append(playSources, filecontents)
+		}
+		for i, seg := range sourceSegs {
+			seg.Anchor = fmt.Sprintf("s%d-%d", len(example.Segs), i)
+		}
+		example.Files = append(example.Files, file)
+		example.Segs = append(example.Segs, sourceSegs)
+	}
+	checkHighlights(&example, dir)
+	goFiles := slices.DeleteFunc(slices.Clone(files), func(file string) bool {
+		return !strings.HasSuffix(file, ".go")
+	})
+	if len(goFiles) > 0 {
+		req := goRequirement(dir, goFiles)
+		// Anything Go 1 builds needs no badge.
+		if req.Version != "go1" {
+			example.GoVersion, example.GoVersionReason = strings.TrimPrefix(req.Version, "go"), req.Reason
+		}
+		if mod := goModVersion(); mod != "" && version.Compare(req.Version, mod) > 0 {
+			example.problemf(req.Path, req.Line, "%s needs %s, newer than the go %s of go.mod", req.Reason, req.Version, strings.TrimPrefix(mod, "go"))
+		}
+	}
+	if slices.ContainsFunc(playFiles, func(file string) bool { return strings.HasSuffix(file, ".go") }) {
+		example.GoCode = playgroundSource(playFiles, playSources)
+	}
+	example.Notes = parseNotes(exampleID, exampleName, notePaths)
+	example.Description = example.Summary
+	for _, segs := range example.Segs {
+		for _, seg := range segs {
+			if example.Description == "" {
+				example.Description = firstSentence(seg.Docs)
+			}
+		}
+	}
+	if example.GoCode == "" {
+		example.problemf("examples/"+exampleID, 0, "no Go source file")
+		return &example
+	}
+	newCodeHash := sha1Sum(example.GoCode)
+	if example.GoCodeHash != newCodeHash {
+		hashPath := "examples/" + example.ID + "/" + example.ID + ".hash"
+		if *offline {
+			p := problem{hashPath, 0, "warning: playground link outdated"}
+			example.warnings = append(example.warnings, p.String())
+			example.URLOutdated = true
+		} else if urlHash, err := resetURLHashFile(backend, newCodeHash, example.GoCode, hashPath); err != nil {
+			example.problemf(hashPath, 0, "updating playground link: %v (use -offline to keep the old one)", err)
+		} else {
+			example.URLHash, example.URLOutdated = urlHash, backend.local()
+		}
+	}
+	return &example
+}
+
+// loadConfig reads the site config from path over the defaults. A missing
+// file is fine unless it was asked for with -config. Settings that also have
+// a flag take the flag's value when it was given.
+func loadConfig(path string) error {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	dat, err := os.ReadFile(path)
+	if os.IsNotExist(err) && !set["config"] {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(dat))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(site); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	for name, value := range map[string]*string{
+		"share-url":  &site.ShareURL,
+		"style":      &site.Style,
+		"dark-style": &site.DarkStyle,
+	} {
+		if set[name] {
+			*value = flag.Lookup(name).Value.String()
+		}
+	}
+	*shareURL, *styleName, *darkStyleName = site.ShareURL, site.Style, site.DarkStyle
+	siteDir = site.SiteDir
+	return nil
+}
+
+// requiredTemplates are the templates the site is rendered from, and
+// siteAssets the files copied next to the pages. Either can be overridden by
+// the theme.
+var requiredTemplates = []string{"meta.tmpl", "footer.tmpl", "index.tmpl", "example.tmpl", "note.tmpl", "tag.tmpl", "all.tmpl", "404.tmpl"}
+var siteAssets = []string{"site.css", "site.js", "favicon.ico", "play.png", "clipboard.png", "search.js", "theme.js"}
+
+// templatePath returns the path of the named template or asset, taking it
+// from the theme if the theme has one.
+func templatePath(name string) string {
+	if *themeDir != "" {
+		path := filepath.Join(*themeDir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join("templates", name)
+}
+
+// checkTheme makes sure the theme exists and that every required template
+// can be found, so a broken theme fails before anything is rendered.
+func checkTheme() error {
+	if *themeDir != "" && !isDir(*themeDir) {
+		return fmt.Errorf("theme directory %s does not exist", *themeDir)
+	}
+	var missing []string
+	for _, name := range requiredTemplates {
+		if _, err := os.Stat(templatePath(name)); err != nil {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		where := "templates/"
+		if *themeDir != "" {
+			where = *themeDir + " or templates/"
+		}
+		return fmt.Errorf("missing required templates in %s: %s", where, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// copyAssets copies the site's assets, then the theme's static folder,
+// keeping its subdirectories.
+func copyAssets() {
+	for _, name := range siteAssets {
+		copyFile(templatePath(name), filepath.Join(siteDir, name))
+	}
+	if *themeDir == "" {
+		return
+	}
+	static := filepath.Join(*themeDir, "static")
+	if !isDir(static) {
+		return
+	}
+	err := filepath.WalkDir(static, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(static, path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(siteDir, rel)
+		ensureDir(filepath.Dir(dst))
+		copyFile(path, dst)
+		return nil
+	})
+	check(err)
+}
+
+// pageMeta describes a page to search engines and link previews; see
+// meta.tmpl.
+type pageMeta struct {
+	Title, Description string
+	// URL is the page's canonical URL, empty without a base URL.
+	URL string
+}
+
+// parseTemplates parses the named templates, in order, into a single
+// template named name.
+func parseTemplates(name string, files ...string) *template.Template {
+	tmpl := template.New(name)
+	for _, file := range files {
+		path := templatePath(file)
+		if _, err := tmpl.Parse(mustReadFile(path)); err != nil {
+			fatalf("%s: %v", path, err)
+		}
+	}
+	return tmpl
+}
+
+func renderIndex(examples []*Example) {
+	var tags []pageLink
+	for _, tag := range groupTags(examples) {
+		tags = append(tags, tag.pageLink)
+	}
+	data := struct {
+		Site     *siteConfig
+		Meta     pageMeta
+		Examples []*Example
+		Sections []*Section
+		Tags     []pageLink
+	}{site, pageMeta{site.Title, site.Description, site.URL("")}, examples, groupSections(examples), tags}
+	if verbose() {
+		fmt.Println("Rendering index")
+	}
+	indexTmpl := parseTemplates("index", "meta.tmpl", "footer.tmpl", "index.tmpl")
+	var buf bytes.Buffer
+	check(indexTmpl.Execute(&buf, data))
+	writeIfChanged(siteDir+"/index.html", buf.Bytes())
+}
+
+func renderExamples(examples []*Example) {
+	if verbose() {
+		fmt.Println("Rendering examples")
+	}
+	metaSrc := mustReadFile(templatePath("meta.tmpl"))
+	footerSrc := mustReadFile(templatePath("footer.tmpl"))
+	exampleSrc := mustReadFile(templatePath("example.tmpl"))
+	exampleTmpl := parseTemplates("example", "meta.tmpl", "footer.tmpl", "example.tmpl")
+	siteJSON, err := json.Marshal(site)
+	check(err)
+	forEach(len(examples), func(i int) {
+		example := examples[i]
+		key := cache.key("page", metaSrc, footerSrc, exampleSrc, string(siteJSON), pageInputs(example))
+		page, ok := cache.get(key)
+		if !ok {
+			data := struct {
+				*Example
+				Site *siteConfig
+				Meta pageMeta
+			}{example, site, pageMeta{site.Title + ": " + example.Name, example.Description, site.URL(example.ID)}}
+			var buf bytes.Buffer
+			check(exampleTmpl.Execute(&buf, data))
+			page = buf.Bytes()
+			cache.put(key, page)
+		}
+		writeIfChanged(siteDir+"/"+example.ID, page)
+	})
+}
+
+// pageInputs serializes everything about an example that its page depends
+// on. Neighbours only contribute what the prev/next links show, so that a
+// change inside one example doesn't invalidate the pages around it, while a
+// renamed or reordered neighbour does.
+func pageInputs(example *Example) string {
+	inputs := struct {
+		Example          Example
+		PrevID, PrevName string
+		NextID, NextName string
+	}{Example: *example}
+	inputs.Example.PrevExample, inputs.Example.NextExample = nil, nil
+	if example.PrevExample != nil {
+		inputs.PrevID, inputs.PrevName = example.PrevExample.ID, example.PrevExample.Name
+	}
+	if example.NextExample != nil {
+		inputs.NextID, inputs.NextName = example.NextExample.ID, example.NextExample.Name
+	}
+	dat, err := json.Marshal(inputs)
+	check(err)
+	return string(dat)
+}
+
+func renderNotes(examples []*Example) {
+	if verbose() {
+		fmt.Println("Rendering notes")
+	}
+	noteTmpl := parseTemplates("note", "meta.tmpl", "footer.tmpl", "note.tmpl")
+	for _, example := range examples {
+		for _, note := range example.Notes {
+			title := site.Title + ": " + note.ExampleName + ": " + note.Title
+			data := struct {
+				*Note
+				Site *siteConfig
+				Meta pageMeta
+			}{note, site, pageMeta{title, note.Description, site.URL(note.ID)}}
+			var buf bytes.Buffer
+			check(noteTmpl.Execute(&buf, data))
+			writeIfChanged(siteDir+"/"+note.ID, buf.Bytes())
+		}
+	}
+}
+
+// Tag is a tag of examples and the examples that have it, in order.
+type Tag struct {
+	pageLink
+	Examples []*Example
+}
+
+// groupTags returns the tags of the examples, sorted by name.
+func groupTags(examples []*Example) []*Tag {
+	byID := make(map[string]*Tag)
+	var tags []*Tag
+	for _, example := range examples {
+		for _, link := range example.Tags {
+			tag := byID[link.ID]
+			if tag == nil {
+				tag = &Tag{pageLink: link}
+				byID[link.ID] = tag
+				tags = append(tags, tag)
+			}
+			tag.Examples = append(tag.Examples, example)
+		}
+	}
+	slices.SortFunc(tags, func(a, b *Tag) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return tags
+}
+
+// renderTags writes a page for each tag, listing the examples that have it.
+func renderTags(examples []*Example) {
+	if verbose() {
+		fmt.Println("Rendering tags")
+	}
+	tagTmpl := parseTemplates("tag", "meta.tmpl", "footer.tmpl", "tag.tmpl")
+	for _, tag := range groupTags(examples) {
+		title := site.Title + ": Tag: " + tag.Name
+		description := fmt.Sprintf("%s examples about %s.", site.Title, tag.Name)
+		data := struct {
+			*Tag
+			Site *siteConfig
+			Meta pageMeta
+		}{tag, site, pageMeta{title, description, site.URL(tag.ID)}}
+		var buf bytes.Buffer
+		check(tagTmpl.Execute(&buf, data))
+		writeIfChanged(siteDir+"/"+tag.ID, buf.Bytes())
+	}
+}
+
+// searchIndex is the inverted index behind the search box, written to
+// search.json. Pages and segments are stored once and referred to by their
+// position, to keep the file small.
+type searchIndex struct {
+	// Pages are [ID, Name] pairs.
+	Pages [][2]string `json:"pages"`
+	// Segs are [page, anchor, snippet] triples.
+	Segs [][3]any `json:"segs"`
+	// Terms map lower-cased words, identifiers and package paths to the
+	// segments containing them.
+	Terms map[string][]int `json:"terms"`
+}
+
+var searchWordPat = regexp.MustCompile(`[\pL_][\pL\pN_]*(\.[\pL_][\pL\pN_]*)*`)
+var searchImportPat = regexp.MustCompile(`(?m)^\s*(?:import\s+)?(?:\w+\s+)?"([\w./-]+)"\s*$`)
+var searchMarkupPat = regexp.MustCompile("\\]\\([^)]*\\)|[`*\\[\\]]")
+
+// searchStopWords are too common in the docs to be worth indexing.
+var searchStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "can": true, "for": true, "from": true, "here": true,
+	"if": true, "in": true, "is": true, "it": true, "its": true, "of": true,
+	"on": true, "or": true, "that": true, "the": true, "this": true, "to": true,
+	"we": true, "with": true, "you": true, "your": true,
+}
+
+// searchTerms returns the terms a piece of docs or code is found by. Dotted
+// names like context.WithTimeout are indexed both whole and by their parts,
+// and imported package paths both whole and by their last element.
+func searchTerms(text string) []string {
+	var terms []string
+	for _, m := range searchImportPat.FindAllStringSubmatch(text, -1) {
+		terms = append(terms, m[1], filepath.Base(m[1]))
+	}
+	for _, word := range searchWordPat.FindAllString(text, -1) {
+		terms = append(terms, word)
+		if strings.Contains(word, ".") {
+			terms = append(terms, strings.Split(word, ".")...)
+		}
+	}
+	var result []string
+	for _, term := range terms {
+		term = strings.ToLower(term)
+		if len(term) > 1 && !searchStopWords[term] {
+			result = append(result, term)
+		}
+	}
+	return result
+}
+
+// searchSnippet returns a short plain-text summary of a segment to show in
+// search results.
+func searchSnippet(seg *Seg) string {
+	text := seg.Docs
+	if text == "" {
+		text = seg.Code
+	}
+	text = strings.Join(strings.Fields(searchMarkupPat.ReplaceAllString(text, "")), " ")
+	if runes := []rune(text); len(runes) > 80 {
+		text = strings.TrimSpace(string(runes[:80])) + "…"
+	}
+	return text
+}
+
+func renderSearchIndex(examples []*Example) {
+	if verbose() {
+		fmt.Println("Rendering search index")
+	}
+	index := searchIndex{Pages: [][2]string{}, Segs: [][3]any{}, Terms: map[string][]int{}}
+	for page, example := range examples {
+		index.Pages = append(index.Pages, [2]string{example.ID, example.Name})
+		// The example's name finds its first segment.
+		text := example.Name
+		for _, segs := range example.Segs {
+			for _, seg := range segs {
+				// Setup code is boilerplate, not what anyone looks for.
+				if seg.Setup {
+					continue
+				}
+				id := len(index.Segs)
+				index.Segs = append(index.Segs, [3]any{page, seg.Anchor, searchSnippet(seg)})
+				// Link targets are left out, or every page linking to
+				// pkg.go.dev would match "go" and "dev".
+				text += "\n" + searchMarkupPat.ReplaceAllString(seg.Docs, " ") + "\n" + seg.Code
+				for _, term := range searchTerms(text) {
+					ids := index.Terms[term]
+					if len(ids) == 0 || ids[len(ids)-1] != id {
+						index.Terms[term] = append(ids, id)
+					}
+				}
+				text = ""
+			}
+		}
+	}
+	dat, err := json.Marshal(index)
+	check(err)
+	writeIfChanged(siteDir+"/search.json", dat)
+}
+
+// chromaCSS returns the CSS for the classes in chromaFormat's output.
+func chromaCSS() string {
+	return styleCSS(lookupStyle(*styleName))
+}
+
+func styleCSS(style *chroma.Style) string {
+	var buf bytes.Buffer
+	check(chromahtml.New(chromahtml.WithClasses(true)).WriteCSS(&buf, style))
+	return buf.String()
+}
+
+// The site's own styles, which highlight only a few token types.
+var _ = styles.Register(chroma.MustNewStyle("gobyexample", chroma.StyleEntries{
+	chroma.Background:    "#252519 bg:#f0f0f0",
+	chroma.Keyword:       "#954121",
+	chroma.KeywordType:   "#b00040",
+	chroma.NameBuiltin:   "#954121",
+	chroma.LiteralNumber: "#666666",
+	chroma.LiteralString: "#219161",
+	chroma.GenericPrompt: "#000080",
+	chroma.GenericOutput: "#808080",
+	chroma.Comment:       "#808080",
+}))
+
+var _ = styles.Register(chroma.MustNewStyle("gobyexample-dark", chroma.StyleEntries{
+	chroma.Background:    "#dadada bg:#282828",
+	chroma.Keyword:       "#af5a54",
+	chroma.KeywordType:   "#b64343",
+	chroma.NameBuiltin:   "#af5a54",
+	chroma.LiteralNumber: "#688ec8",
+	chroma.LiteralString: "#718e72",
+	chroma.GenericPrompt: "#8a6ab1",
+	chroma.GenericOutput: "#868686",
+	chroma.Comment:       "#868686",
+}))
+
+func lookupStyle(name string) *chroma.Style {
+	style, ok := styles.Registry[name]
+	if !ok {
+		fatalf("unknown chroma style %q; available styles: %s", name, strings.Join(styles.Names(), ", "))
+	}
+	return style
+}
+
+var cssRulePat = regexp.MustCompile(`(?m)^(/\* [^*]* \*/ )?(\.[^{]*\{)`)
+
+// highlightCSS returns the stylesheet for highlighted code. The dark style's
+// rules are scoped to dark mode: either the system preference, unless the
+// reader picked light mode, or their explicit choice, which theme.js marks on
+// the root element.
+func highlightCSS(light, dark *chroma.Style) string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "/* Generated by tools/generate from the chroma style %q. */\n", light.Name)
+	fmt.Fprintf(&buf, ":root { --code-bg: %s; }\n", light.Get(chroma.Background).Background)
+	buf.WriteString(styleCSS(light))
+	if dark == nil {
+		return buf.String()
+	}
+	scoped := func(scope string) string {
+		css := fmt.Sprintf(":root%s { --code-bg: %s; }\n", scope, dark.Get(chroma.Background).Background)
+		return css + cssRulePat.ReplaceAllString(styleCSS(dark), "${1}:root"+scope+" ${2}")
+	}
+	fmt.Fprintf(&buf, "\n/* Dark mode, from the chroma style %q. */\n", dark.Name)
+	buf.WriteString("@media (prefers-color-scheme: dark) {\n")
+	buf.WriteString(scoped(`:not([data-theme="light"])`))
+	buf.WriteString("}\n")
+	buf.WriteString(scoped(`[data-theme="dark"]`))
+	return buf.String()
+}
+
+func renderHighlightCSS() {
+	var dark *chroma.Style
+	if *darkStyleName != "" {
+		dark = lookupStyle(*darkStyleName)
+	}
+	css := highlightCSS(lookupStyle(*styleName), dark)
+	writeIfChanged(siteDir+"/highlight.css", []byte(css))
+}
+
+// xhtmlEntityPat matches the named character references in rendered HTML.
+// XHTML only knows the five predefined by XML, so the others are rewritten
+// as numeric references.
+var xhtmlEntityPat = regexp.MustCompile(`&([a-zA-Z][a-zA-Z0-9]*);`)
+
+func xhtml(src string) string {
+	return xhtmlEntityPat.ReplaceAllStringFunc(src, func(ref string) string {
+		switch ref {
+		case "&amp;", "&lt;", "&gt;", "&quot;", "&apos;":
+			return ref
+		}
+		if r := []rune(html.UnescapeString(ref)); len(r) == 1 && ref != string(r) {
+			return fmt.Sprintf("&#%d;", r[0])
+		}
+		return "&amp;" + ref[1:]
+	})
+}
+
+var exampleLinkPat = regexp.MustCompile(`href="([a-z0-9-]*)(#[^"]*)?"`)
+
+// relinkExamples returns copies of the examples whose docs link to other
+// examples at link(id, fragment) rather than at their relative URLs, for
+// outputs where examples aren't pages of the site. Links within an example,
+// like those to its highlight groups, are relinked with its own id.
+func relinkExamples(examples []*Example, link func(id, fragment string) string) []*Example {
+	ids := make(map[string]bool)
+	for _, example := range examples {
+		ids[example.ID] = true
+	}
+	copies := make([]*Example, len(examples))
+	for i, example := range examples {
+		copied := *example
+		copied.Segs = nil
+		for _, segs := range example.Segs {
+			var copiedSegs []*Seg
+			for _, seg := range segs {
+				seg := *seg
+				seg.DocsRendered = exampleLinkPat.ReplaceAllStringFunc(seg.DocsRendered, func(href string) string {
+					m := exampleLinkPat.FindStringSubmatch(href)
+					id := m[1]
+					if id == "" && m[2] != "" {
+						id = example.ID
+					}
+					if !ids[id] {
+						return href
+					}
+					return `href="` + link(id, m[2]) + `"`
+				})
+				copiedSegs = append(copiedSegs, &seg)
+			}
+			copied.Segs = append(copied.Segs, copiedSegs)
+		}
+		copies[i] = &copied
+	}
+	return copies
+}
+
+const epubCSS = `body { font-family: serif; line-height: 1.4; }
+h1 { font-size: 1.6em; margin: 1em 0 0.5em; }
+div.docs { margin-top: 0.8em; }
+p.file { font-family: monospace; font-size: 0.8em; color: #808080; margin-top: 1.2em; }
+pre { font-family: monospace; font-size: 0.8em; white-space: pre-wrap;
+  background: #f0f0f0; padding: 0.4em; margin: 0.4em 0; }
+`
+
+var epubTmpl = template.Must(template.New("epub").Funcs(template.FuncMap{"xml": xmlEscape}).Parse(`
+{{define "container"}}<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+{{end}}
+{{define "opf"}}<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="book-id">{{.ID}}</dc:identifier>
+    <dc:title>{{xml .Site.Title}}</dc:title>
+{{- range .Site.Authors}}
+    <dc:creator>{{xml .Name}}</dc:creator>
+{{- end}}
+    <dc:language>en</dc:language>
+    <meta property="dcterms:modified">{{.Modified}}</meta>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="style" href="style.css" media-type="text/css"/>
+{{- range .Examples}}
+    <item id="ex-{{.ID}}" href="{{.ID}}.xhtml" media-type="application/xhtml+xml"/>
+{{- end}}
+  </manifest>
+  <spine>
+    <itemref idref="nav"/>
+{{- range .Examples}}
+    <itemref idref="ex-{{.ID}}"/>
+{{- end}}
+  </spine>
+</package>
+{{end}}
+{{define "nav"}}<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="en" xml:lang="en">
+  <head>
+    <title>{{xml .Site.Title}}</title>
+    <link rel="stylesheet" type="text/css" href="style.css"/>
+  </head>
+  <body>
+    <nav epub:type="toc" id="toc">
+      <h1>{{xml .Site.Title}}</h1>
+      <ol>
+{{- range .Examples}}
+        <li><a href="{{.ID}}.xhtml">{{xml .Name}}</a></li>
+{{- end}}
+      </ol>
+    </nav>
+  </body>
+</html>
+{{end}}
+{{define "chapter"}}<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" lang="en" xml:lang="en">
+  <head>
+    <title>{{xml .Name}}</title>
+    <link rel="stylesheet" type="text/css" href="style.css"/>
+  </head>
+  <body>
+    <h1>{{xml .Name}}</h1>
+{{- range $i, $segs := .Segs}}
+    <p class="file">{{xml (index $.Files $i)}}</p>
+{{- range $segs}}
+{{- if .Docs}}
+    <div class="docs">{{.DocsRendered}}</div>
+{{- end}}
+{{- if and .Code (not .Setup)}}
+    <div class="code">{{.CodeRendered}}</div>
+{{- end}}
+{{- end}}{{end}}
+  </body>
+</html>
+{{end}}`))
+
+func xmlEscape(s string) string {
+	var buf strings.Builder
+	check(xml.EscapeText(&buf, []byte(s)))
+	return buf.String()
+}
+
+// writeEPUB packages the examples, in order, as an EPUB 3 book: one XHTML
+// chapter per example, built from the rendered segments, a navigation
+// document and a stylesheet including the highlighting CSS.
+func writeEPUB(w io.Writer, examples []*Example) {
+	// Links between examples point at their chapters, and the rendered HTML
+	// is made valid XHTML.
+	chapters := relinkExamples(examples, func(id, fragment string) string {
+		return id + ".xhtml" + fragment
+	})
+	for _, chapter := range chapters {
+		for _, segs := range chapter.Segs {
+			for _, seg := range segs {
+				seg.DocsRendered = xhtml(seg.DocsRendered)
+				seg.CodeRendered = xhtml(seg.CodeRendered)
+			}
+		}
+	}
+
+	modified := time.Now().UTC()
+	if epoch, err := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64); err == nil {
+		modified = time.Unix(epoch, 0).UTC()
+	}
+	var names []string
+	for _, example := range examples {
+		names = append(names, example.ID)
+	}
+	data := struct {
+		Site         *siteConfig
+		ID, Modified string
+		Examples     []*Example
+	}{
+		Site:     site,
+		ID:       "urn:sha1:" + sha1Sum(strings.Join(names, "\n")),
+		Modified: modified.Format("2006-01-02T15:04:05Z"),
+		Examples: chapters,
+	}
+
+	zw := zip.NewWriter(w)
+	// The mimetype has to come first and be stored uncompressed, so that
+	// readers can identify the file by its leading bytes.
+	mw, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	check(err)
+	_, err = io.WriteString(mw, "application/epub+zip")
+	check(err)
+	add := func(name string, render func(w io.Writer)) {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+		check(err)
+		render(fw)
+	}
+	add("META-INF/container.xml", func(w io.Writer) { check(epubTmpl.ExecuteTemplate(w, "container", data)) })
+	add("OEBPS/content.opf", func(w io.Writer) { check(epubTmpl.ExecuteTemplate(w, "opf", data)) })
+	add("OEBPS/nav.xhtml", func(w io.Writer) { check(epubTmpl.ExecuteTemplate(w, "nav", data)) })
+	add("OEBPS/style.css", func(w io.Writer) {
+		_, err := io.WriteString(w, epubCSS+chromaCSS())
+		check(err)
+	})
+	for _, chapter := range chapters {
+		add("OEBPS/"+chapter.ID+".xhtml", func(w io.Writer) { check(epubTmpl.ExecuteTemplate(w, "chapter", chapter)) })
+	}
+	check(zw.Close())
+}
+
+func renderEPUB(examples []*Example, path string) {
+	if verbose() {
+		fmt.Println("Rendering EPUB to " + path)
+	}
+	var buf bytes.Buffer
+	writeEPUB(&buf, examples)
+	writeIfChanged(path, buf.Bytes())
+}
+
+// renderAll renders all examples into all.html, a single page for printing
+// and offline reading. Links between examples become links within the page,
+// where the anchors of each example are prefixed with its id to keep them
+// unique.
+func renderAll(examples []*Example) {
+	if verbose() {
+		fmt.Println("Rendering all examples")
+	}
+	tmpl := parseTemplates("all", "meta.tmpl", "footer.tmpl", "all.tmpl")
+	all := relinkExamples(examples, func(id, fragment string) string {
+		if fragment == "" || fragment == "#" {
+			return "#" + id
+		}
+		return "#" + id + "-" + fragment[1:]
+	})
+	for _, example := range all {
+		for _, segs := range example.Segs {
+			for _, seg := range segs {
+				seg.CodeRendered = strings.ReplaceAll(seg.CodeRendered, ` id="hl-`, ` id="`+example.ID+`-hl-`)
+			}
+		}
+	}
+	data := struct {
+		Site     *siteConfig
+		Meta     pageMeta
+		Examples []*Example
+	}{site, pageMeta{site.Title + ": All Examples", site.Description, site.URL("all.html")}, all}
+	var buf bytes.Buffer
+	check(tmpl.Execute(&buf, data))
+	writeIfChanged(siteDir+"/all.html", buf.Bytes())
+}
+
+var mdExampleLinkPat = regexp.MustCompile(`\]\(([a-z0-9-]+)(#[^)\s]*)?\)`)
+
+// exampleMarkdown renders an example as GitHub-flavored Markdown: docs as
+// prose and code in fenced blocks, one section per source file. Links to
+// other examples point at their Markdown files. Setup code is folded away
+// in a details element, as on the site.
+func exampleMarkdown(example *Example, ids map[string]bool) string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "# %s\n", example.Name)
+	for i, segs := range example.Segs {
+		fmt.Fprintf(&buf, "\n### %s\n", example.Files[i])
+		// Code of consecutive segments without docs goes in one block.
+		fence := ""
+		for _, seg := range segs {
+			if seg.Docs != "" {
+				if fence != "" {
+					buf.WriteString("```\n")
+					fence = ""
+				}
+				docs := mdExampleLinkPat.ReplaceAllStringFunc(seg.Docs, func(link string) string {
+					m := mdExampleLinkPat.FindStringSubmatch(link)
+					if !ids[m[1]] {
+						return link
+					}
+					return "](" + m[1] + ".md" + m[2] + ")"
+				})
+				buf.WriteString("\n" + docs + "\n")
+			}
+			if seg.Setup {
+				if fence != "" {
+					buf.WriteString("```\n")
+					fence = ""
+				}
+				fmt.Fprintf(&buf, "\n<details><summary>… setup code</summary>\n\n```%s\n%s\n```\n\n</details>\n", seg.Lexer, seg.Code)
+			} else if seg.Code != "" {
+				if fence == "" {
+					fence = seg.Lexer
+					buf.WriteString("\n```" + fence + "\n")
+				} else {
+					buf.WriteString("\n")
+				}
+				buf.WriteString(strings.Trim(seg.Code, "\n") + "\n")
+			}
+		}
+		if fence != "" {
+			buf.WriteString("```\n")
+		}
+	}
+	return buf.String()
+}
+
+// renderMarkdown writes every example as a Markdown file to dir, along with
+// a README.md that lists them all.
+func renderMarkdown(examples []*Example, dir string) {
+	if verbose() {
+		fmt.Println("Rendering Markdown to " + dir)
+	}
+	ensureDir(dir)
+	ids := make(map[string]bool)
+	for _, example := range examples {
+		ids[example.ID] = true
+	}
+	forEach(len(examples), func(i int) {
+		example := examples[i]
+		writeIfChanged(filepath.Join(dir, example.ID+".md"), []byte(exampleMarkdown(example, ids)))
+	})
+	var index strings.Builder
+	fmt.Fprintf(&index, "# %s\n", site.Title)
+	for _, section := range groupSections(examples) {
+		if section.Title != "" {
+			fmt.Fprintf(&index, "\n## %s\n", section.Title)
+		}
+		index.WriteString("\n")
+		for _, example := range section.Examples {
+			fmt.Fprintf(&index, "- [%s](%s.md)\n", example.Name, example.ID)
+		}
+	}
+	writeIfChanged(filepath.Join(dir, "README.md"), []byte(index.String()))
+}
+
+// sitemap is the sitemap.xml schema, see https://www.sitemaps.org/protocol.html.
+type sitemap struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc string `xml:"loc"`
+}
+
+// renderSitemap lists the index, every example and their notes in
+// sitemap.xml. Without a base URL there is nothing to list them by.
+func renderSitemap(examples []*Example) {
+	if site.BaseURL == "" {
+		return
+	}
+	if verbose() {
+		fmt.Println("Rendering sitemap")
+	}
+	m := sitemap{URLs: []sitemapURL{{site.URL("")}}}
+	for _, example := range examples {
+		m.URLs = append(m.URLs, sitemapURL{site.URL(example.ID)})
+		for _, note := range example.Notes {
+			m.URLs = append(m.URLs, sitemapURL{site.URL(note.ID)})
+		}
+	}
+	for _, tag := range groupTags(examples) {
+		m.URLs = append(m.URLs, sitemapURL{site.URL(tag.ID)})
+	}
+	m.URLs = append(m.URLs, sitemapURL{site.URL("all.html")})
+	dat, err := xml.MarshalIndent(m, "", "  ")
+	check(err)
+	writeIfChanged(siteDir+"/sitemap.xml", append([]byte(xml.Header), append(dat, '\n')...))
+}
+
+func render404() {
+	if verbose() {
+		fmt.Println("Rendering 404")
+	}
+	tmpl := parseTemplates("404", "footer.tmpl", "404.tmpl")
+	data := struct {
+		Site *siteConfig
+	}{site}
+	var buf bytes.Buffer
+	check(tmpl.Execute(&buf, data))
+	writeIfChanged(siteDir+"/404.html", buf.Bytes())
+}
+
+// checklistItem is an entry of the learning checklist in examples-index.md.
+// Everything a reader adds to an entry by hand is kept on regeneration: the
+// done mark, text after the link and indented lines below it, like the
+// "Usage" links.
+type checklistItem struct {
+	done  bool
+	extra string
+	notes []string
+	line  int
+}
+
+var checklistItemPat = regexp.MustCompile(`^- \[([ xX])\] \d+\. \[[^\]]*\]\(\./examples/([^/)]+)/[^)]*\)(.*)$`)
+
+// parseChecklist reads an existing examples-index.md. It returns the intro
+// before the first section, the items by example ID, and the text after the
+// list, which starts at a "---" line.
+func parseChecklist(path string) (string, map[string]*checklistItem, string) {
+	items := make(map[string]*checklistItem)
+	dat, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "# Go by Example - Interactive Learning Index\n", items, ""
+	}
+	check(err)
+	lines := strings.Split(string(dat), "\n")
+	var intro, trailer []string
+	var last *checklistItem
+	inList := false
+	for i, line := range lines {
+		switch {
+		case !inList && strings.HasPrefix(line, "## "):
+			inList = true
+		case !inList:
+			intro = append(intro, line)
+		case line == "---":
+			trailer = lines[i:]
+		}
+		if trailer != nil {
+			break
+		}
+		if m := checklistItemPat.FindStringSubmatch(line); m != nil {
+			last = &checklistItem{done: m[1] != " ", extra: m[3], line: i + 1}
+			items[m[2]] = last
+		} else if last != nil && strings.TrimSpace(line) != "" && strings.TrimLeft(line, " \t") != line {
+			last.notes = append(last.notes, line)
+		} else {
+			last = nil
+		}
+	}
+	return strings.Join(intro, "\n"), items, strings.Join(trailer, "\n")
+}
+
+// checklistSource returns the path of the file an index entry links to, the
+// example's main Go file.
+func checklistSource(id string) string {
+	main := "examples/" + id + "/" + id + ".go"
+	if _, err := os.Stat(main); err == nil {
+		return main
+	}
+	if paths := mustGlob("examples/" + id + "/*.go"); len(paths) > 0 {
+		return paths[0]
+	}
+	return main
+}
+
+// writeChecklist regenerates examples-index.md from examples.txt and the
+// sections in examples-plan.txt. It reports and returns the examples that
+// are only in one of these lists.
+func writeChecklist() []problem {
+	const indexPath = "examples-index.md"
+	const planPath = "examples-plan.txt"
+	exampleNames, exampleLines, _ := readExampleNames()
+	intro, items, trailer := parseChecklist(indexPath)
+
+	var problems []problem
+	known := make(map[string]bool)
+	for _, name := range exampleNames {
+		known[name] = true
+	}
+	sections := make(map[string]string)
+	for i, line := range readLines(planPath) {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, title, ok := strings.Cut(line, "|")
+		name, title = strings.TrimSpace(name), strings.TrimSpace(title)
+		if !ok || title == "" {
+			problems = append(problems, problem{planPath, i + 1, "expected \"<example> | <section title>\""})
+		} else if !known[name] {
+			problems = append(problems, problem{planPath, i + 1, fmt.Sprintf("%s is not in examples.txt", name)})
+		} else {
+			sections[name] = title
+		}
+	}
+
+	var buf strings.Builder
+	buf.WriteString(strings.TrimRight(intro, "\n") + "\n")
+	for i, name := range exampleNames {
+		id := exampleID(name)
+		if title, ok := sections[name]; ok || i == 0 {
+			if !ok {
+				title = "Examples"
+			}
+			fmt.Fprintf(&buf, "\n## %s\n\n", title)
+		}
+		item, ok := items[id]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "examples.txt:%d: %s added to %s\n", exampleLines[i], name, indexPath)
+			item = &checklistItem{}
+		}
+		delete(items, id)
+		mark := " "
+		if item.done {
+			mark = "x"
+		}
+		fmt.Fprintf(&buf, "- [%s] %d. [%s](./%s)%s\n", mark, i+1, name, checklistSource(id), item.extra)
+		for _, note := range item.notes {
+			buf.WriteString(note + "\n")
+		}
+	}
+	var removed []string
+	for id := range items {
+		removed = append(removed, id)
+	}
+	slices.SortFunc(removed, func(a, b string) int { return items[a].line - items[b].line })
+	for _, id := range removed {
+		problems = append(problems, problem{indexPath, items[id].line, fmt.Sprintf("%s is not in examples.txt; dropping it", id)})
+	}
+	if trailer != "" {
+		buf.WriteString("\n" + trailer)
+	}
+
+	for _, p := range problems {
+		fmt.Fprintln(os.Stderr, p)
+	}
+	writeIfChanged(indexPath, []byte(buf.String()))
+	return problems
+}
+
+func main() {
+	flag.Parse()
+	if err := loadConfig(*configPath); err != nil {
+		fatalf("%v", err)
+	}
+	if *checklist {
+		if len(writeChecklist()) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+	if *checkLinks {
+		// Nothing is shared or written but the render cache.
+		*offline = true
+		cache = newRenderCache(*cacheDir)
+		problems := checkDocLinks(parseExamples(hashShare{}))
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+		if len(problems) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+	if flag.NArg() > 0 {
+		siteDir = flag.Arg(0)
+	}
+	cache = newRenderCache(*cacheDir)
+	backend := limitedShare{newShareBackend(*shareName), make(chan struct{}, max(*shareJobs, 1))}
+	os.Exit(generate(backend, os.Stderr))
+}
+
+// generate renders the site into siteDir, reporting problems to w, and
+// returns the exit status. Problems in the examples stop it before anything
+// is written; with -keep-going the other examples are rendered, but the
+// status still says that something was wrong.
+func generate(backend shareBackend, w io.Writer) int {
+	if err := checkTheme(); err != nil {
+		fmt.Fprintln(w, "generate:", err)
+		return 1
+	}
+	examples, ok := reportProblems(w, parseExamples(backend))
+	if !ok && !*keepGoing {
+		return 1
+	}
+	ensureDir(siteDir)
+	copyAssets()
+	renderHighlightCSS()
+	renderSite(examples)
+	if !ok {
+		return 1
+	}
+	return 0
+}
+
+// renderSite renders the pages of the examples and everything generated
+// from them into siteDir.
+func renderSite(examples []*Example) {
+	linkExamples(examples)
+	renderIndex(examples)
+	renderExamples(examples)
+	renderNotes(examples)
+	renderTags(examples)
+	renderSearchIndex(examples)
+	renderAll(examples)
+	renderSitemap(examples)
+	if *epubPath != "" {
+		renderEPUB(examples, *epubPath)
+	}
+	if *markdownDir != "" {
+		renderMarkdown(examples, *markdownDir)
+	}
 	render404()
 }
 
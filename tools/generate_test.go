@@ -0,0 +1,1141 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/chroma/v2"
+)
+
+func renderTestExample(id, name, docs, code string) *Example {
+	seg := &Seg{Docs: docs, Code: code}
+	seg.DocsRendered = markdown(seg.Docs)
+	seg.CodeRendered = chromaFormat(seg.Code, id+".go")
+	return &Example{ID: id, Name: name, Files: []string{id + ".go"}, Segs: [][]*Seg{{seg}}}
+}
+
+// parseTestSource writes src to a file called name in a temporary directory
+// and parses it as an example source file.
+func parseTestSource(t *testing.T, name, src string) (string, []*Seg, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	segs, source := parseSegs(path)
+	return path, segs, source
+}
+
+// chdirTestRepo changes into a temporary directory holding files, by
+// slash-separated path, like a checkout of the repository with a go.mod.
+// The render cache goes to a temporary directory too.
+func chdirTestRepo(t *testing.T, files map[string]string) {
+	t.Helper()
+	cache = &renderCache{dir: t.TempDir()}
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("go.mod", []byte("module m\n\ngo 1.25\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(path.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// checkXML fails the test unless src is well-formed XML that needs no
+// entities beyond the ones XML predefines.
+func checkXML(t *testing.T, name string, src []byte) {
+	t.Helper()
+	d := xml.NewDecoder(bytes.NewReader(src))
+	for {
+		_, err := d.Token()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestWriteEPUB(t *testing.T) {
+	examples := []*Example{
+		renderTestExample("hello-world", "Hello World",
+			"Our first program will print the classic \"hello world\" message -- it's a start.",
+			"package main\n\nfunc main() {\n\tprintln(\"hello world\")\n}"),
+		renderTestExample("time-formatting-parsing", "Time Formatting / Parsing",
+			"Go supports time formatting, see also [Hello World](hello-world) & [Go](https://go.dev).",
+			"t := time.Now()"),
+	}
+	var buf bytes.Buffer
+	writeEPUB(&buf, examples)
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name], err = io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first := zr.File[0]
+	if first.Name != "mimetype" || first.Method != zip.Store || string(files["mimetype"]) != "application/epub+zip" {
+		t.Errorf("first entry is %s (method %d), want a stored mimetype", first.Name, first.Method)
+	}
+
+	var container struct {
+		Rootfiles []struct {
+			FullPath  string `xml:"full-path,attr"`
+			MediaType string `xml:"media-type,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	if err := xml.Unmarshal(files["META-INF/container.xml"], &container); err != nil {
+		t.Fatal(err)
+	}
+	if len(container.Rootfiles) != 1 || container.Rootfiles[0].MediaType != "application/oebps-package+xml" {
+		t.Fatalf("container.xml rootfiles = %+v", container.Rootfiles)
+	}
+	opfPath := container.Rootfiles[0].FullPath
+
+	var opf struct {
+		Version    string `xml:"version,attr"`
+		UniqueID   string `xml:"unique-identifier,attr"`
+		Identifier []struct {
+			ID    string `xml:"id,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"metadata>identifier"`
+		Title string `xml:"metadata>title"`
+		Meta  []struct {
+			Property string `xml:"property,attr"`
+			Value    string `xml:",chardata"`
+		} `xml:"metadata>meta"`
+		Items []struct {
+			ID         string `xml:"id,attr"`
+			Href       string `xml:"href,attr"`
+			MediaType  string `xml:"media-type,attr"`
+			Properties string `xml:"properties,attr"`
+		} `xml:"manifest>item"`
+		Spine []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"spine>itemref"`
+	}
+	if err := xml.Unmarshal(files[opfPath], &opf); err != nil {
+		t.Fatal(err)
+	}
+	if opf.Version != "3.0" || opf.Title == "" {
+		t.Errorf("package version %q, title %q", opf.Version, opf.Title)
+	}
+	if len(opf.Identifier) != 1 || opf.Identifier[0].ID != opf.UniqueID || opf.Identifier[0].Value == "" {
+		t.Errorf("unique-identifier %q doesn't name a dc:identifier in %+v", opf.UniqueID, opf.Identifier)
+	}
+	modified := false
+	for _, m := range opf.Meta {
+		modified = modified || (m.Property == "dcterms:modified" && len(m.Value) == len("2006-01-02T15:04:05Z"))
+	}
+	if !modified {
+		t.Errorf("no dcterms:modified in %+v", opf.Meta)
+	}
+
+	dir := path.Dir(opfPath)
+	manifest := make(map[string]string)
+	navs := 0
+	for _, item := range opf.Items {
+		name := path.Join(dir, item.Href)
+		if _, ok := files[name]; !ok {
+			t.Errorf("manifest item %s: %s isn't in the book", item.ID, name)
+		}
+		if item.MediaType == "application/xhtml+xml" {
+			checkXML(t, name, files[name])
+		}
+		if item.Properties == "nav" {
+			navs++
+		}
+		manifest[item.ID] = item.Href
+	}
+	if navs != 1 {
+		t.Errorf("%d nav documents, want 1", navs)
+	}
+	var spine []string
+	for _, ref := range opf.Spine {
+		href, ok := manifest[ref.IDRef]
+		if !ok {
+			t.Errorf("spine item %s isn't in the manifest", ref.IDRef)
+		}
+		spine = append(spine, href)
+	}
+	want := "nav.xhtml hello-world.xhtml time-formatting-parsing.xhtml"
+	if got := strings.Join(spine, " "); got != want {
+		t.Errorf("spine = %s, want %s", got, want)
+	}
+
+	nav := string(files[path.Join(dir, "nav.xhtml")])
+	if !strings.Contains(nav, `epub:type="toc"`) || !strings.Contains(nav, `href="time-formatting-parsing.xhtml">Time Formatting / Parsing<`) {
+		t.Errorf("nav.xhtml lacks the table of contents:\n%s", nav)
+	}
+	chapter := string(files[path.Join(dir, "time-formatting-parsing.xhtml")])
+	if !strings.Contains(chapter, `href="hello-world.xhtml"`) || !strings.Contains(chapter, `href="https://go.dev"`) {
+		t.Errorf("links between examples weren't rewritten:\n%s", chapter)
+	}
+	if css := string(files[path.Join(dir, "style.css")]); !strings.Contains(css, ".chroma") {
+		t.Errorf("style.css lacks the highlighting CSS:\n%s", css)
+	}
+}
+
+func TestXHTML(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"a &ldquo;b&rdquo; &amp; c", "a &#8220;b&#8221; &amp; c"},
+		{"&lt;x&gt; &#34;", "&lt;x&gt; &#34;"},
+		{"&nosuchentity;", "&amp;nosuchentity;"},
+	}
+	for _, tt := range tests {
+		if got := xhtml(tt.in); got != tt.want {
+			t.Errorf("xhtml(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFirstSentence(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"_Slices_ are a key type. They wrap arrays.", "Slices are a key type."},
+		{"# Title\n\nUse [`sync.WaitGroup`](https://pkg.go.dev/sync#WaitGroup) to wait!", "Use sync.WaitGroup to wait!"},
+		{"Sends (chan <-)\nblock <em>until</em> received", "Sends (chan <-) block until received"},
+		{"```go\ncode\n```\n\nThen prose.", "Then prose."},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := firstSentence(tt.in); got != tt.want {
+			t.Errorf("firstSentence(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLinkDocs(t *testing.T) {
+	imports := map[string]string{"rand": "math/rand/v2"}
+	tests := []struct {
+		in, want string
+		unknown  []string
+	}{
+		{"Use [sync.WaitGroup] to wait.", "Use [sync.WaitGroup](https://pkg.go.dev/sync#WaitGroup) to wait.", nil},
+		{"See [*bytes.Buffer.String].", "See [*bytes.Buffer.String](https://pkg.go.dev/bytes#Buffer.String).", nil},
+		{"`errors.As` and `rand.IntN()`", "[`errors.As`](https://pkg.go.dev/errors#As) and [`rand.IntN()`](https://pkg.go.dev/math/rand/v2#IntN)", nil},
+		{"[`time.Tick`](https://example.com) stays", "[`time.Tick`](https://example.com) stays", nil},
+		{"[sync](https://pkg.go.dev/sync) and `wg.Add`", "[sync](https://pkg.go.dev/sync) and `wg.Add`", nil},
+		{"`sync.WaitGrop` and [nopkg.Thing]", "`sync.WaitGrop` and [nopkg.Thing]", []string{"nopkg.Thing", "sync.WaitGrop"}},
+	}
+	for _, tt := range tests {
+		got, unknown := linkDocs(tt.in, imports)
+		if got != tt.want {
+			t.Errorf("linkDocs(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		if !slices.Equal(unknown, tt.unknown) {
+			t.Errorf("linkDocs(%q) unknown = %q, want %q", tt.in, unknown, tt.unknown)
+		}
+	}
+}
+
+func TestParseSegsCommentSyntax(t *testing.T) {
+	tests := []struct {
+		name, src  string
+		docs, code []string
+	}{
+		{"q.sql", "-- Pick rows.\nSELECT * FROM t; -- inline\n", []string{"Pick rows."}, []string{"SELECT * FROM t; -- inline"}},
+		{"c.yaml", "# A setting.\nkey: value\n", []string{"A setting."}, []string{"key: value"}},
+		{"d.json", "{\"a\": \"# not docs\"}\n", []string{""}, []string{"{\"a\": \"# not docs\"}"}},
+		{"go.mod", "// Docs.\nmodule m\n", []string{"Docs."}, []string{"module m"}},
+		{"b.go", "/* Block\n\n   docs. */\nx := 1 /* inline */\n", []string{"Block\n\n   docs."}, []string{"x := 1 /* inline */"}},
+		{"e.go", "f()\n\t/*\n\t\tindented\n\t*/\n", []string{"\n    indented"}, []string{"f()"}},
+		{"c.go", "/*\nfunc f() {}\n*/\n", []string{"```go\nfunc f() {}\n```"}, []string{""}},
+		{"u.go", "/* unterminated\nx := 1\n", []string{""}, []string{"/* unterminated\nx := 1"}},
+		{"t.sh", "# Run.\n$ go run t.go\nreal 0m2.1s # RE: real 0m2\\.\\d+s\n", []string{"Run."}, []string{"$ go run t.go\nreal 0m2.1s"}},
+	}
+	for _, tt := range tests {
+		_, segs, _ := parseTestSource(t, tt.name, tt.src)
+		var docs, code []string
+		for _, seg := range segs {
+			docs = append(docs, seg.Docs)
+			code = append(code, seg.Code)
+		}
+		if !slices.Equal(docs, tt.docs) || !slices.Equal(code, tt.code) {
+			t.Errorf("%s: docs %q, code %q; want %q, %q", tt.name, docs, code, tt.docs, tt.code)
+		}
+	}
+}
+
+// TestBlockCommentProse pins which block comments are shown as Go code:
+// only those that are Go declarations as a whole. Code indented within prose
+// stays part of the prose, and Markdown decides how to show it.
+func TestBlockCommentProse(t *testing.T) {
+	tests := []struct {
+		name, comment string
+		goCode, pre   bool
+	}{
+		{"declarations", "var ErrNotFound = errors.New(\"not found\")\n\nfunc f() {}", true, true},
+		{"indented declarations", "    type T struct{}", true, true},
+		{"statements", "if errors.Is(err, ErrNotFound) {\n    return\n}", false, false},
+		{"prose", "Use errors.Is() to compare against a sentinel.", false, false},
+		// From custom-errors: the snippet continues the paragraph.
+		{"snippet after a line", "❌ NEVER do this (without sentinel):\n" +
+			"    if errors.Is(err, &InvalidOTPError{}) {  // Creates NEW pointer each time!\n" +
+			"        // This is UNRELIABLE - different pointer addresses\n" +
+			"    }", false, false},
+		// After a blank line, it's a Markdown code block, but not Go's.
+		{"snippet after a blank line", "Example:\n\n    var ErrNotFound = errors.New(\"not found\")", false, true},
+		{"heading rules", "=====\nERRORS.IS vs ERRORS.AS\n=====\n\nKEY DIFFERENCE:\n- errors.Is: identity", false, false},
+	}
+	for _, tt := range tests {
+		_, segs, _ := parseTestSource(t, "b.go", "package main\n\n/*\n"+tt.comment+"\n*/\n")
+		if len(segs) != 2 {
+			t.Fatalf("%s: got %d segments, want 2", tt.name, len(segs))
+		}
+		docs := segs[1].Docs
+		if goCode := strings.HasPrefix(docs, "```go\n"); goCode != tt.goCode {
+			t.Errorf("%s: shown as Go code %v, want %v:\n%s", tt.name, goCode, tt.goCode, docs)
+		}
+		if pre := strings.Contains(markdown(docs), "<pre>"); pre != tt.pre {
+			t.Errorf("%s: rendered as a code block %v, want %v:\n%s", tt.name, pre, tt.pre, markdown(docs))
+		}
+	}
+}
+
+func TestSetupRegion(t *testing.T) {
+	src := "package main\n\n// SETUP\n\n// A helper.\nfunc helper() {} // HL\n\n// END SETUP\n\n// Docs.\nfunc main() {}\n"
+	_, segs, source := parseTestSource(t, "setup.go", src)
+	wantSource := "package main\n\n\n// A helper.\nfunc helper() {}\n\n\n// Docs.\nfunc main() {}\n"
+	if source != wantSource {
+		t.Errorf("source = %q, want %q", source, wantSource)
+	}
+	if len(segs) != 3 {
+		t.Fatalf("got %d segments, want 3", len(segs))
+	}
+	setup := segs[1]
+	if !setup.Setup || setup.Docs != "" || setup.Code != "// A helper.\nfunc helper() {}" {
+		t.Errorf("setup segment = %+v", setup)
+	}
+	if want := []Highlight{{2, ""}}; !slices.Equal(setup.Highlights, want) {
+		t.Errorf("setup highlights = %v, want %v", setup.Highlights, want)
+	}
+	if segs[0].Setup || segs[2].Setup || segs[2].Docs != "Docs." {
+		t.Errorf("segments around the setup region = %+v, %+v", segs[0], segs[2])
+	}
+}
+
+func TestFrontMatter(t *testing.T) {
+	src := "// ---\n// tags: a, b c,\n// difficulty: beginner\n// summary: Sums: fast.\n// related: x\n// colour: red\n// ---\n\n// Docs.\npackage main\n"
+	path, segs, source := parseTestSource(t, "fm.go", src)
+	if source != "// Docs.\npackage main\n" || len(segs) != 1 || segs[0].Docs != "Docs." {
+		t.Errorf("front matter left in source %q or segments %+v", source, segs)
+	}
+	example := &Example{}
+	meta := exampleMeta{Related: []string{"y"}}
+	readFrontMatter(example, path, &meta)
+	if !slices.Equal(meta.Tags, []string{"a", "b c"}) || meta.Difficulty != "beginner" || meta.Summary != "Sums: fast." {
+		t.Errorf("meta = %+v", meta)
+	}
+	var msgs []string
+	for _, p := range example.problems {
+		msgs = append(msgs, p.String())
+	}
+	want := []string{path + ":5: related is set more than once", path + ":6: unknown front matter key \"colour\""}
+	if !slices.Equal(msgs, want) {
+		t.Errorf("problems = %q, want %q", msgs, want)
+	}
+	if pos := meta.pos["difficulty"]; pos != (metaPos{path, 3}) {
+		t.Errorf("difficulty set at %v, want %s:3", pos, path)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.WriteFile(dir+"/meta.json", []byte("{\n  \"tags\": [\"a\"],\n  \"related\": [\"x\"]\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	meta = readExampleMeta(example, dir)
+	if pos := meta.pos["related"]; pos != (metaPos{dir + "/meta.json", 3}) {
+		t.Errorf("related set at %v, want %s/meta.json:3", pos, dir)
+	}
+}
+
+func TestGoRequirement(t *testing.T) {
+	cache = &renderCache{dir: t.TempDir()}
+	dir := t.TempDir()
+	tests := []struct {
+		src, version, reason string
+		line, column         int
+	}{
+		{"package main\nimport \"fmt\"\nfunc main() { fmt.Println(1) }\n", "go1", "package fmt", 2, 8},
+		{"package main\nfunc f[T any](t T) {}\n", "go1.18", "type parameters", 2, 7},
+		{"package main\nfunc main() { _ = min(1, 2)\n_ = 0b1 }\n", "go1.21", "min", 2, 19},
+		{"package main\nfunc main() { for range 3 {} }\n", "go1.22", "range over int", 2, 15},
+		{"package main\nimport \"slices\"\nfunc main() {\nfor range slices.Values([]int{}) {} }\n", "go1.23", "range over function", 4, 1},
+		{"package main\nimport \"sync\"\nfunc main() { var wg sync.WaitGroup\nwg.Go(func() {}) }\n", "go1.25", "sync.WaitGroup.Go", 4, 4},
+	}
+	for i, tt := range tests {
+		name := fmt.Sprintf("r%d.go", i)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(tt.src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		got := goRequirement(dir, []string{name})
+		want := requirement{tt.version, tt.reason, dir + "/" + name, tt.line, tt.column}
+		if got != want {
+			t.Errorf("%q: got %+v, want %+v", tt.src, got, want)
+		}
+	}
+}
+
+func TestHighlightMarkers(t *testing.T) {
+	src := "// Sum [the loop](#hl-loop).\nfor i := range 3 { // HL:loop\n\tsum += i // HL\n}\n"
+	path, segs, source := parseTestSource(t, "hl.go", src)
+	if strings.Contains(source, "HL") || strings.Contains(segs[0].Code, "HL") {
+		t.Errorf("marker left in source %q or code %q", source, segs[0].Code)
+	}
+	want := []Highlight{{1, "loop"}, {2, ""}}
+	if !slices.Equal(segs[0].Highlights, want) {
+		t.Errorf("highlights = %v, want %v", segs[0].Highlights, want)
+	}
+	rendered := chromaFormat(segs[0].Code, path, segs[0].Highlights...)
+	if n := strings.Count(rendered, `class="line hl"`); n != 2 {
+		t.Errorf("%d highlighted lines, want 2:\n%s", n, rendered)
+	}
+	if !strings.Contains(rendered, `<span class="line hl" id="hl-loop" data-hl="loop">`) {
+		t.Errorf("no named group in:\n%s", rendered)
+	}
+}
+
+func TestPlayShare(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) == "fail" {
+			http.Error(w, "no", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, "key-"+sha1Sum(string(body))[:4])
+	}))
+	defer srv.Close()
+	backend := playShare{url: srv.URL}
+	if key, err := backend.share("package main"); err != nil || key != "key-"+sha1Sum("package main")[:4] {
+		t.Errorf("share = %q, %v", key, err)
+	}
+	if key, err := backend.share("fail"); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("share of a failing request = %q, %v; want a 500 error", key, err)
+	}
+}
+
+func TestStaleHash(t *testing.T) {
+	cache = &renderCache{dir: t.TempDir()}
+	t.Chdir(t.TempDir())
+	if err := os.MkdirAll("examples/hi", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("go.mod", []byte("module m\n\ngo 1.25\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("examples/hi/hi.go", []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stale := "0000000000000000000000000000000000000000\nold\n"
+	if err := os.WriteFile("examples/hi/hi.hash", []byte(stale), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		offline bool
+		urlHash string
+	}{
+		{true, "old"},
+		{false, sha1Sum("package main\n\nfunc main() {}\n")[:11]},
+	}
+	defer func(old bool) { *offline = old }(*offline)
+	for _, tt := range tests {
+		*offline = tt.offline
+		example := parseExample("Hi", 1, hashShare{})
+		if !example.URLOutdated || example.URLHash != tt.urlHash {
+			t.Errorf("offline %v: URL hash %q, outdated %v; want %q, outdated", tt.offline, example.URLHash, example.URLOutdated, tt.urlHash)
+		}
+		if dat, _ := os.ReadFile("examples/hi/hi.hash"); string(dat) != stale {
+			t.Errorf("offline %v: hash file rewritten to %q", tt.offline, dat)
+		}
+	}
+}
+
+func TestParseHashFile(t *testing.T) {
+	tests := []struct {
+		content, hash, key string
+		ok                 bool
+	}{
+		{"abc\nkey\n", "abc", "key", true},
+		{"abc\nkey", "abc", "key", true},
+		{"abc\n", "", "", false},
+		{"abc", "", "", false},
+		{"\nkey\n", "", "", false},
+		{"", "", "", false},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "x.hash")
+		if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		hash, key, ok := parseHashFile(path)
+		if hash != tt.hash || key != tt.key || ok != tt.ok {
+			t.Errorf("parseHashFile(%q) = %q, %q, %v; want %q, %q, %v", tt.content, hash, key, ok, tt.hash, tt.key, tt.ok)
+		}
+	}
+}
+
+func TestRenderCachePut(t *testing.T) {
+	c := &renderCache{dir: t.TempDir()}
+	key := c.key("page")
+	c.put(key, []byte("old"))
+	c.put(key, []byte("new"))
+	if dat, ok := c.get(key); !ok || string(dat) != "new" {
+		t.Errorf("get = %q, %v; want \"new\"", dat, ok)
+	}
+	entries, err := os.ReadDir(filepath.Dir(c.path(key)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("cache directory has %d entries, want only the page", len(entries))
+	}
+}
+
+// TestJobsDeterministic renders the site with one worker and with many, and
+// checks that the results are the same byte for byte.
+func TestJobsDeterministic(t *testing.T) {
+	t.Chdir("..")
+	defer func(j int, o bool, dir string) { *jobs, *offline, siteDir = j, o, dir }(*jobs, *offline, siteDir)
+	*offline = true
+	render := func(j int) string {
+		*jobs = j
+		siteDir = t.TempDir()
+		cache = &renderCache{dir: t.TempDir()}
+		renderSite(parseExamples(hashShare{}))
+		return siteDir
+	}
+	files := func(dir string) map[string]string {
+		files := make(map[string]string)
+		err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			dat, err := os.ReadFile(path)
+			files[strings.TrimPrefix(path, dir)] = string(dat)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return files
+	}
+	one, many := files(render(1)), files(render(16))
+	if len(one) < 50 || len(one) != len(many) {
+		t.Fatalf("rendered %d files with -j 1 and %d with -j 16", len(one), len(many))
+	}
+	for name, dat := range one {
+		if many[name] != dat {
+			t.Errorf("%s differs between -j 1 and -j 16", name)
+		}
+	}
+}
+
+func TestGenerateProblems(t *testing.T) {
+	templates, err := filepath.Abs("../templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(o, k bool, theme, dir string) {
+		*offline, *keepGoing, *themeDir, siteDir = o, k, theme, dir
+	}(*offline, *keepGoing, *themeDir, siteDir)
+	*offline, *themeDir = true, templates
+	files := map[string]string{
+		"examples.txt":              "Hello\nMissing\nNo Go\nOdd\n",
+		"examples/hello/hello.go":   "package main\n\nfunc main() {}\n",
+		"examples/no-go/no-go.sh":   "$ true\n",
+		"examples/odd/odd.go":       "package main\n\nfunc main() {}\n",
+		"examples/odd/odd.bin":      "",
+		"examples/odd/meta.json":    "{\n  \"difficulty\": \"hard\"\n}\n",
+		"examples/hello/hello.hash": sha1Sum("package main\n\nfunc main() {}\n") + "\nkey\n",
+		"examples/odd/odd.hash":     sha1Sum("package main\n\nfunc main() {}\n") + "\n",
+	}
+	problems := []string{
+		"examples.txt:2: no example directory examples/missing",
+		"examples/no-go: no Go source file",
+		"examples/odd/odd.hash: warning: playground link outdated",
+		"examples/odd/odd.bin: unsupported file type",
+		"examples/odd/odd.hash:1: malformed hash file, expected a code hash and a URL hash line",
+		"examples/odd/meta.json:2: unknown difficulty \"hard\", want one of beginner, intermediate, advanced",
+		"5 problem(s) in 3 example(s)",
+	}
+	tests := []struct {
+		name      string
+		examples  string
+		keepGoing bool
+		reported  []string
+		status    int
+		rendered  bool
+	}{
+		{"valid", "Hello\n", false, nil, 0, true},
+		{"problems", files["examples.txt"], false, problems, 1, false},
+		{"problems, keep going", files["examples.txt"], true, problems, 1, true},
+	}
+	for _, tt := range tests {
+		files["examples.txt"] = tt.examples
+		chdirTestRepo(t, files)
+		*keepGoing = tt.keepGoing
+		siteDir = "public"
+		var out bytes.Buffer
+		status := generate(hashShare{}, &out)
+		reported := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+		if out.Len() == 0 {
+			reported = nil
+		}
+		if status != tt.status || !slices.Equal(reported, tt.reported) {
+			t.Errorf("%s: status %d, reported:\n%s\nwant %d:\n%s", tt.name, status, out.String(), tt.status, strings.Join(tt.reported, "\n"))
+		}
+		if _, err := os.Stat("public/hello"); (err == nil) != tt.rendered {
+			t.Errorf("%s: hello rendered %v, want %v", tt.name, err == nil, tt.rendered)
+		}
+		if _, err := os.Stat("public"); !tt.rendered && err == nil {
+			t.Errorf("%s: site directory written despite problems", tt.name)
+		}
+		if _, err := os.Stat("public/odd"); err == nil {
+			t.Errorf("%s: example with problems rendered", tt.name)
+		}
+	}
+}
+
+func TestTheme(t *testing.T) {
+	defer func(theme, dir string) { *themeDir, siteDir = theme, dir }(*themeDir, siteDir)
+	files := map[string]string{
+		"theme/index.tmpl":        "{{define \"index\"}}themed{{end}}",
+		"theme/static/img/bg.png": "png",
+	}
+	for _, name := range requiredTemplates {
+		if name != "index.tmpl" && name != "404.tmpl" {
+			files["templates/"+name] = ""
+		}
+	}
+	chdirTestRepo(t, files)
+
+	*themeDir = "nosuch"
+	if err := checkTheme(); err == nil || err.Error() != "theme directory nosuch does not exist" {
+		t.Errorf("checkTheme with a missing theme = %v", err)
+	}
+	*themeDir = "theme"
+	if got := templatePath("index.tmpl"); got != filepath.Join("theme", "index.tmpl") {
+		t.Errorf("templatePath(index.tmpl) = %s, want the theme's", got)
+	}
+	if got := templatePath("meta.tmpl"); got != filepath.Join("templates", "meta.tmpl") {
+		t.Errorf("templatePath(meta.tmpl) = %s, want the default", got)
+	}
+	want := "missing required templates in theme or templates/: 404.tmpl"
+	if err := checkTheme(); err == nil || err.Error() != want {
+		t.Errorf("checkTheme = %v, want %s", err, want)
+	}
+	if err := os.WriteFile("theme/404.tmpl", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkTheme(); err != nil {
+		t.Errorf("checkTheme with 404.tmpl in the theme = %v", err)
+	}
+
+	siteDir = "public"
+	for _, name := range siteAssets {
+		if err := os.WriteFile("templates/"+name, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ensureDir(siteDir)
+	copyAssets()
+	if dat, err := os.ReadFile("public/img/bg.png"); err != nil || string(dat) != "png" {
+		t.Errorf("theme's static file copied as %q, %v", dat, err)
+	}
+	if dat, err := os.ReadFile("public/site.css"); err != nil || string(dat) != "site.css" {
+		t.Errorf("asset copied as %q, %v", dat, err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	defer func(c siteConfig, dir, config, share, style, dark string) {
+		*site, siteDir, *configPath, *shareURL, *styleName, *darkStyleName = c, dir, config, share, style, dark
+	}(*site, siteDir, *configPath, *shareURL, *styleName, *darkStyleName)
+	chdirTestRepo(t, map[string]string{
+		"site.json":      `{"title": "Go Notes", "style": "monokai", "shareURL": "https://share.example/", "siteDir": "out"}`,
+		"unknown.json":   `{"title": "Go Notes", "colour": "red"}`,
+		"malformed.json": `{"title": "Go Notes",`,
+		"wrongtype.json": `{"title": 1}`,
+	})
+
+	// Without the file, the defaults stay.
+	if err := loadConfig("nosuch.json"); err != nil || site.Title != "Go by Example" {
+		t.Errorf("loadConfig of a missing file: %v, title %q", err, site.Title)
+	}
+
+	// A flag given on the command line wins over the file; the file wins
+	// over the flag's default.
+	if err := flag.Set("style", "dracula"); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadConfig("site.json"); err != nil {
+		t.Fatal(err)
+	}
+	if site.Title != "Go Notes" || siteDir != "out" {
+		t.Errorf("title %q, site dir %q; want the file's", site.Title, siteDir)
+	}
+	if site.Style != "dracula" || *styleName != "dracula" {
+		t.Errorf("style %q, -style %q; want the flag's", site.Style, *styleName)
+	}
+	if site.ShareURL != "https://share.example/" || *shareURL != "https://share.example/" {
+		t.Errorf("share URL %q, -share-url %q; want the file's", site.ShareURL, *shareURL)
+	}
+	if site.DarkStyle != "gobyexample-dark" || *darkStyleName != "gobyexample-dark" {
+		t.Errorf("dark style %q, -dark-style %q; want the default", site.DarkStyle, *darkStyleName)
+	}
+
+	// Errors name the file, and unknown keys are errors too.
+	for path, want := range map[string]string{
+		"unknown.json":   `unknown field "colour"`,
+		"malformed.json": "unexpected EOF",
+		"wrongtype.json": "siteConfig.title",
+	} {
+		if err := loadConfig(path); err == nil || !strings.HasPrefix(err.Error(), path+": ") || !strings.Contains(err.Error(), want) {
+			t.Errorf("loadConfig(%s) = %v, want an error about %s", path, err, want)
+		}
+	}
+
+	// Asked for with -config, the file has to exist.
+	if err := flag.Set("config", "nosuch.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadConfig("nosuch.json"); !os.IsNotExist(err) {
+		t.Errorf("loadConfig of a missing -config file = %v, want it not to exist", err)
+	}
+}
+
+var cssCommentPat = regexp.MustCompile(`^/\* [^*]* \*/ `)
+
+func TestHighlightCSS(t *testing.T) {
+	light, dark := lookupStyle("gobyexample"), lookupStyle("gobyexample-dark")
+	if css := highlightCSS(light, nil); strings.Contains(css, "@media") || strings.Contains(css, "data-theme") {
+		t.Errorf("without a dark style, got dark rules:\n%s", css)
+	}
+
+	// Rules go to the light style, the dark one for the system preference,
+	// or the dark one the reader picked, by where they are.
+	const (
+		lightScope  = ""
+		systemScope = `:root:not([data-theme="light"])`
+		pickedScope = `:root[data-theme="dark"]`
+	)
+	rules := make(map[string][]string)
+	scope := lightScope
+	for _, line := range strings.Split(strings.TrimSpace(highlightCSS(light, dark)), "\n") {
+		switch {
+		case strings.HasPrefix(line, "/* Generated"), strings.HasPrefix(line, "/* Dark mode"), line == "":
+			continue
+		case strings.HasPrefix(line, "@media (prefers-color-scheme: dark)"):
+			scope = systemScope
+			continue
+		case line == "}":
+			scope = pickedScope
+			continue
+		}
+		rule := cssCommentPat.ReplaceAllString(line, "")
+		scoped := strings.HasPrefix(rule, scope+" ")
+		if scope == lightScope {
+			scoped = strings.HasPrefix(rule, ".") || strings.HasPrefix(rule, ":root {")
+		}
+		if !scoped {
+			t.Errorf("rule %q is not scoped to %q", line, scope)
+		}
+		rules[scope] = append(rules[scope], strings.TrimPrefix(rule, scope+" "))
+	}
+	if len(rules[lightScope]) != strings.Count(styleCSS(light), "\n")+1 {
+		t.Errorf("%d light rules, want the style's and --code-bg", len(rules[lightScope]))
+	}
+	if !slices.Equal(rules[systemScope], rules[pickedScope]) || len(rules[systemScope]) != strings.Count(styleCSS(dark), "\n")+1 {
+		t.Errorf("dark rules differ between scopes or from the style:\n%q\n%q", rules[systemScope], rules[pickedScope])
+	}
+	for scope, style := range map[string]*chroma.Style{lightScope: light, systemScope: dark, pickedScope: dark} {
+		bg := style.Get(chroma.Background).Background.String()
+		if want := "{ --code-bg: " + bg + "; }"; !strings.HasSuffix(rules[scope][0], want) {
+			t.Errorf("first rule of %q is %q, want %q", scope, rules[scope][0], want)
+		}
+	}
+}
+
+func TestGroupSections(t *testing.T) {
+	chdirTestRepo(t, map[string]string{
+		"examples.txt": "Hello World\n\n## Basics\nValues\n# A comment.\nConstants\n\n## Concurrency\nGoroutines\n## Empty\n## Basics\nMore Values\n",
+	})
+	names, _, titles := readExampleNames()
+	var examples []*Example
+	for i, name := range names {
+		example := &Example{ID: exampleID(name), Name: name, Section: titles[i]}
+		if titles[i] != "" {
+			example.SectionID = exampleID(titles[i])
+		}
+		examples = append(examples, example)
+	}
+	var got []string
+	for _, section := range groupSections(examples) {
+		var ids []string
+		for _, example := range section.Examples {
+			ids = append(ids, example.ID)
+		}
+		got = append(got, fmt.Sprintf("%q %q: %s", section.ID, section.Title, strings.Join(ids, " ")))
+	}
+	// Examples before the first heading are in a section with no title, and
+	// headings with no examples make no section. A section split by another
+	// one is listed twice.
+	want := []string{
+		`"" "": hello-world`,
+		`"basics" "Basics": values constants`,
+		`"concurrency" "Concurrency": goroutines`,
+		`"basics" "Basics": more-values`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("sections:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+var htmlIDPat = regexp.MustCompile(`\bid="([^"]*)"`)
+var htmlFragmentPat = regexp.MustCompile(`\bhref="#([^"]+)"`)
+
+func TestRenderAll(t *testing.T) {
+	templates, err := filepath.Abs("../templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(theme, dir string) { *themeDir, siteDir = theme, dir }(*themeDir, siteDir)
+	*themeDir, siteDir = templates, t.TempDir()
+	// Both examples have a highlight group called loop.
+	example := func(id, name, docs string) *Example {
+		example := renderTestExample(id, name, docs, "for {\n\tbreak\n}")
+		seg := example.Segs[0][0]
+		seg.Anchor = "s0-0"
+		seg.Highlights = []Highlight{{1, "loop"}, {2, "loop"}}
+		seg.CodeRendered = chromaFormat(seg.Code, id+".go", seg.Highlights...)
+		return example
+	}
+	examples := []*Example{
+		example("for", "For", "Links to [the loop](#hl-loop) and [Go](https://go.dev)."),
+		example("range", "Range", "Like [for](for), or [its loop](for#hl-loop), [this loop](#hl-loop)."),
+	}
+	renderAll(examples)
+	dat, err := os.ReadFile(filepath.Join(siteDir, "all.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(dat)
+
+	ids := make(map[string]bool)
+	for _, m := range htmlIDPat.FindAllStringSubmatch(page, -1) {
+		if ids[m[1]] {
+			t.Errorf("id %q is repeated", m[1])
+		}
+		ids[m[1]] = true
+	}
+	for _, id := range []string{"for", "for-s0-0", "for-hl-loop", "range", "range-s0-0", "range-hl-loop"} {
+		if !ids[id] {
+			t.Errorf("no element with id %q", id)
+		}
+	}
+	var fragments []string
+	for _, m := range htmlFragmentPat.FindAllStringSubmatch(page, -1) {
+		if !ids[m[1]] {
+			t.Errorf("link to #%s, which isn't on the page", m[1])
+		}
+		fragments = append(fragments, m[1])
+	}
+	// The table of contents, then the links in the docs.
+	want := []string{"for", "range", "for-hl-loop", "for", "for-hl-loop", "range-hl-loop"}
+	if !slices.Equal(fragments, want) {
+		t.Errorf("links to %q, want %q", fragments, want)
+	}
+	if !strings.Contains(page, `href="https://go.dev"`) {
+		t.Error("external link was changed")
+	}
+	// The examples themselves are left as they were.
+	if docs := examples[1].Segs[0][0].DocsRendered; !strings.Contains(docs, `href="for#hl-loop"`) {
+		t.Errorf("relinking changed the example's docs to %s", docs)
+	}
+}
+
+func TestSearchTerms(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Use `context.WithTimeout` to limit it.", []string{"use", "context.withtimeout", "context", "withtimeout", "limit"}},
+		{"import (\n\t\"fmt\"\n\tstdhttp \"net/http\"\n)", []string{"fmt", "fmt", "net/http", "http", "import", "fmt", "stdhttp", "net", "http"}},
+		{"x := Größe_2 + a", []string{"größe_2"}},
+	}
+	for _, tt := range tests {
+		if got := searchTerms(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("searchTerms(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRenderSearchIndex(t *testing.T) {
+	defer func(dir string) { siteDir = dir }(siteDir)
+	siteDir = t.TempDir()
+	examples := []*Example{
+		{ID: "timers", Name: "Timers", Segs: [][]*Seg{{
+			{Docs: "Wait with [`time.Sleep`](https://pkg.go.dev/time#Sleep).", Anchor: "s0-0"},
+			{Code: "type clock struct{}", Setup: true, Anchor: "s0-1"},
+			{Code: "import \"time\"\n\nfunc main() { time.Sleep(1) }", Anchor: "s0-2"},
+		}}},
+		{ID: "http-server", Name: "HTTP Server", Segs: [][]*Seg{{
+			{Code: "import \"net/http\"", Anchor: "s0-0"},
+		}, {
+			{Docs: "Run the _server_.", Code: "$ go run http-server.go", Anchor: "s1-0"},
+		}}},
+	}
+	renderSearchIndex(examples)
+	var index struct {
+		Pages [][2]string      `json:"pages"`
+		Segs  [][3]any         `json:"segs"`
+		Terms map[string][]int `json:"terms"`
+	}
+	dat, err := os.ReadFile(filepath.Join(siteDir, "search.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(dat, &index); err != nil {
+		t.Fatal(err)
+	}
+	if want := [][2]string{{"timers", "Timers"}, {"http-server", "HTTP Server"}}; !slices.Equal(index.Pages, want) {
+		t.Errorf("pages = %q, want %q", index.Pages, want)
+	}
+	// Results jump to the segment's anchor on the example's page; setup code
+	// isn't indexed.
+	var segs []string
+	for _, seg := range index.Segs {
+		segs = append(segs, fmt.Sprintf("%s#%s: %s", index.Pages[int(seg[0].(float64))][0], seg[1], seg[2]))
+	}
+	wantSegs := []string{
+		"timers#s0-0: Wait with time.Sleep.",
+		"timers#s0-2: import \"time\" func main() { time.Sleep(1) }",
+		"http-server#s0-0: import \"net/http\"",
+		"http-server#s1-0: Run the _server_.",
+	}
+	if !slices.Equal(segs, wantSegs) {
+		t.Errorf("segments:\n%s\nwant:\n%s", strings.Join(segs, "\n"), strings.Join(wantSegs, "\n"))
+	}
+	for term, want := range map[string][]int{
+		// The name finds the first segment.
+		"timers": {0},
+		"server": {2, 3},
+		// Identifiers, whole and by their parts, from docs and code.
+		"time.sleep": {0, 1},
+		"sleep":      {0, 1},
+		"main":       {1},
+		// Packages, by path and by name.
+		"net/http": {2},
+		"http":     {2, 3},
+		"time":     {0, 1},
+		// Words, but not the common ones, link targets or setup code.
+		"wait":  {0},
+		"with":  nil,
+		"dev":   nil,
+		"clock": nil,
+	} {
+		if got := index.Terms[term]; !slices.Equal(got, want) {
+			t.Errorf("term %q finds segments %v, want %v", term, got, want)
+		}
+	}
+}
+
+func TestWriteChecklist(t *testing.T) {
+	t.Chdir(t.TempDir())
+	files := map[string]string{
+		"examples.txt":      "Hello World\nValues\n\n## Later\nGoroutines\nNew One\n",
+		"examples-plan.txt": "# Sections.\nHello World | Basics (Day 1)\nGoroutines | Concurrency\nGone | Nowhere\n",
+		"examples-index.md": "# Index\n\nIntro.\n\n## Old Section\n\n" +
+			"- [x] 1. [Hello World](./examples/hello-world/hello-world.go) - done!\n" +
+			"  - Usage: `go run hello-world.go`\n" +
+			"- [ ] 2. [Values](./examples/values/values.go)\n" +
+			"- [X] 3. [Removed](./examples/removed/removed.go)\n" +
+			"- [x] 4. [Goroutines](./examples/goroutines/goroutines.go)\n" +
+			"\t- Usage: `go run goroutines.go`\n" +
+			"\n---\n\nTrailer.\n",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(name, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	problems := writeChecklist()
+	var msgs []string
+	for _, p := range problems {
+		msgs = append(msgs, p.String())
+	}
+	want := []string{
+		"examples-plan.txt:4: Gone is not in examples.txt",
+		"examples-index.md:10: removed is not in examples.txt; dropping it",
+	}
+	if !slices.Equal(msgs, want) {
+		t.Errorf("problems = %q, want %q", msgs, want)
+	}
+	got, err := os.ReadFile("examples-index.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantIndex := "# Index\n\nIntro.\n\n## Basics (Day 1)\n\n" +
+		"- [x] 1. [Hello World](./examples/hello-world/hello-world.go) - done!\n" +
+		"  - Usage: `go run hello-world.go`\n" +
+		"- [ ] 2. [Values](./examples/values/values.go)\n" +
+		"\n## Concurrency\n\n" +
+		"- [x] 3. [Goroutines](./examples/goroutines/goroutines.go)\n" +
+		"\t- Usage: `go run goroutines.go`\n" +
+		"- [ ] 4. [New One](./examples/new-one/new-one.go)\n" +
+		"\n---\n\nTrailer.\n"
+	if string(got) != wantIndex {
+		t.Errorf("examples-index.md =\n%s\nwant\n%s", got, wantIndex)
+	}
+
+	// Regenerating keeps everything as it is.
+	writeChecklist()
+	if again, _ := os.ReadFile("examples-index.md"); !bytes.Equal(again, got) {
+		t.Errorf("regenerated examples-index.md =\n%s\nwant\n%s", again, got)
+	}
+}
+
+func TestExampleMarkdown(t *testing.T) {
+	example := &Example{
+		Name:  "Channels",
+		Files: []string{"channels.go", "channels.sh"},
+		Segs: [][]*Seg{
+			{
+				{Docs: "See [goroutines](goroutines#s0-1) and [elsewhere](nosuch).", Code: "package main", Lexer: "go"},
+				{Code: "import \"fmt\"", Lexer: "go"},
+				{Code: "\nfunc main() {}\n", Lexer: "go"},
+				{Docs: "Done.", Lexer: "go"},
+			},
+			{
+				{Docs: "Run it.", Code: "$ go run channels.go\nping", Lexer: "console"},
+			},
+		},
+	}
+	want := "# Channels\n" +
+		"\n### channels.go\n" +
+		"\nSee [goroutines](goroutines.md#s0-1) and [elsewhere](nosuch).\n" +
+		"\n```go\npackage main\n\nimport \"fmt\"\n\nfunc main() {}\n```\n" +
+		"\nDone.\n" +
+		"\n### channels.sh\n" +
+		"\nRun it.\n" +
+		"\n```console\n$ go run channels.go\nping\n```\n"
+	got := exampleMarkdown(example, map[string]bool{"goroutines": true, "channels": true})
+	if got != want {
+		t.Errorf("exampleMarkdown =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestNoteIDs checks the page IDs of notes against the list that the tests
+// of checklinks.go use too.
+func TestNoteIDs(t *testing.T) {
+	tests := []struct{ example, path, want string }{
+		{"generics", "CONCEPTS.md", "generics-concepts"},
+		{"generics", "generics-EVOLUTION.md", "generics-evolution"},
+		{"generics", "Generics-Tips.md", "generics-tips"},
+		{"generics", "more/Deep.md", "generics-deep"},
+		{"hello-world", "README.md", "hello-world-readme"},
+	}
+	root := t.TempDir()
+	for _, tt := range tests {
+		path := filepath.Join(root, tt.example, filepath.FromSlash(tt.path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("# Note\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		notes := parseNotes(tt.example, tt.example, []string{path})
+		if notes[0].ID != tt.want {
+			t.Errorf("note %s of %s has ID %s, want %s", tt.path, tt.example, notes[0].ID, tt.want)
+		}
+	}
+}
+
+func TestCheckDocLinks(t *testing.T) {
+	defer func(old bool) { *offline = old }(*offline)
+	*offline = true
+	chdirTestRepo(t, map[string]string{
+		"examples.txt": "Hello World\nMutexes\n",
+		"examples/hello-world/hello-world.go": "// See [mutexes](mutexes), [the notes](mutexes-notes#why),\n" +
+			"// [a tag](tag-sync) and [everything](all.html).\n" +
+			"package main\n\n" +
+			"// Not [a page](mutexs), nor [a site](https://go.dev/).\n" +
+			"func main() { f([]int{}) }\n\n" +
+			"func f[T any](s []T) {}\n\n" +
+			"/*\nA block [comment](nosuch).\n*/\n" +
+			"/*\nfunc g() { h[int](x) }\n*/\n" +
+			"// SETUP\n// [setup](insetup)\n// END SETUP\n",
+		"examples/hello-world/hello-world.sh": "# Back to [hello](hello-world) and `[x](nope)`.\n" +
+			"# Again [not a page](mutexs).\n$ go run .\n",
+		"examples/hello-world/db/q.sql": "-- ---\n-- summary: [meta](inmeta)\n-- ---\n-- Rows [of](nosql).\nSELECT 1;\n",
+		"examples/hello-world/c.yaml":   "# See [it](noyaml).\nk: v\n",
+		"examples/mutexes/mutexes.go":   "// ---\n// tags: sync\n// ---\npackage main\n\nfunc main() {}\n",
+		"examples/mutexes/notes.md":     "# Notes\n\nSee [other](OTHER.md) and [hello](hello-world).\n\n```go\nf[int](s)\n```\n",
+	})
+	examples := parseExamples(hashShare{})
+	for _, id := range []string{"all.html", "hello-world", "mutexes", "mutexes-notes", "tag-sync"} {
+		if !pageIDs(examples)[id] {
+			t.Errorf("pageIDs lacks %s", id)
+		}
+	}
+	var got []string
+	for _, p := range checkDocLinks(examples) {
+		got = append(got, p.String())
+	}
+	want := []string{
+		"examples/hello-world/hello-world.go:5: link to unknown example \"mutexs\"",
+		"examples/hello-world/hello-world.go:11: link to unknown example \"nosuch\"",
+		"examples/hello-world/c.yaml:1: link to unknown example \"noyaml\"",
+		"examples/hello-world/db/q.sql:4: link to unknown example \"nosql\"",
+		"examples/hello-world/hello-world.sh:2: link to unknown example \"mutexs\"",
+		"examples/mutexes/notes.md:3: link to missing note \"OTHER.md\"",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestTagID(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Type Parameters", "tag-type-parameters"},
+		{"a<b>", "tag-ab"},
+		{"C++ & Go", "tag-c-go"},
+		{"I/O", "tag-i-o"},
+		{"<\"'>", "tag-"},
+	}
+	for _, tt := range tests {
+		if got := tagID(tt.in); got != tt.want {
+			t.Errorf("tagID(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
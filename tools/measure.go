@@ -28,18 +28,36 @@ func isDir(path string) bool {
 
 var commentPat = regexp.MustCompile("\\s*\\/\\/")
 
+// transcriptREPat matches the regexp marker that tools/transcripts matches
+// a line of expected output with instead. The site doesn't show it, so it
+// isn't measured either.
+var transcriptREPat = regexp.MustCompile(`\s+# RE: .*$`)
+
 func main() {
-	sourcePaths, err := filepath.Glob("./examples/*/*")
+	// Examples can have files in subdirectories, like the files
+	// embed-directive embeds, and those are shown on the site too.
+	var sourcePaths []string
+	err := filepath.WalkDir("examples", func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && filepath.Dir(path) != "examples" {
+			sourcePaths = append(sourcePaths, path)
+		}
+		return err
+	})
 	check(err)
 	foundLongFile := false
 	for _, sourcePath := range sourcePaths {
 		foundLongLine := false
-		if !isDir(sourcePath) {
+		// Markdown notes are rendered as pages of their own, outside of the
+		// narrow code column, so their line length doesn't matter.
+		if !isDir(sourcePath) && filepath.Ext(sourcePath) != ".md" {
 			lines := readLines(sourcePath)
 			for i, line := range lines {
 				// Convert tabs to spaces before measuring, so we get an accurate measure
 				// of how long the output will end up being.
 				line := strings.Replace(line, "\t", "    ", -1)
+				if filepath.Ext(sourcePath) == ".sh" {
+					line = transcriptREPat.ReplaceAllString(line, "")
+				}
 				if !foundLongLine && !commentPat.MatchString(line) && (utf8.RuneCountInString(line) > 58) {
 					fmt.Printf("measure: %s:%d\n", sourcePath, i+1)
 					foundLongLine = true
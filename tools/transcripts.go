@@ -0,0 +1,385 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// transcripts runs the shell transcripts of the examples, their .sh files,
+// and checks that the commands in them still print what the transcripts
+// show. Each transcript runs as one shell script in a temporary copy of its
+// example, so that later commands see the files and the exit status that
+// earlier ones left behind.
+//
+// Expected output can mark the parts that differ from run to run. A line
+// "..." matches any number of lines, and "..." within a line matches any
+// text. A line ending in "# RE: <regexp>" matches the regexp instead; the
+// site shows the line without the marker. Exit statuses are checked through
+// the output too: `go run` prints "exit status N" when the program fails,
+// and a binary run directly can be followed by `echo $?`.
+
+var timeout = flag.Duration("timeout", time.Minute, "timeout of each transcript")
+var network = flag.Bool("network", false, "also run the examples that use the network")
+
+// problem is a transcript that doesn't match, reported as file:line.
+type problem struct {
+	path string
+	line int
+	msg  string
+}
+
+func (p problem) String() string {
+	return fmt.Sprintf("%s:%d: %s", p.path, p.line, p.msg)
+}
+
+func check(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "transcripts:", err)
+		os.Exit(1)
+	}
+}
+
+// command is a command of a transcript and the output it's expected to
+// print.
+type command struct {
+	line   int // in the transcript
+	cmd    string
+	output []string
+}
+
+// docsPat matches comment lines, which are the docs of a transcript. It
+// must match the one generate.go uses for .sh files.
+var docsPat = regexp.MustCompile(`^\s*#(\s|$)`)
+
+// rePat matches a regexp marker at the end of a line of expected output.
+// generate.go strips the same markers.
+var rePat = regexp.MustCompile(`\s+# RE: (.*)$`)
+
+// parseTranscript returns the commands of a transcript. The output of a
+// command is everything up to the next command but docs, so it can go on
+// after a comment.
+func parseTranscript(src string) []command {
+	var cmds []command
+	for i, line := range strings.Split(src, "\n") {
+		if cmd, ok := strings.CutPrefix(line, "$ "); ok {
+			cmds = append(cmds, command{line: i + 1, cmd: strings.TrimSpace(cmd)})
+			continue
+		}
+		if len(cmds) == 0 || docsPat.MatchString(line) {
+			continue
+		}
+		last := &cmds[len(cmds)-1]
+		last.output = append(last.output, strings.TrimRight(line, " \t"))
+	}
+	for i := range cmds {
+		cmds[i].output = trimBlank(cmds[i].output)
+	}
+	return cmds
+}
+
+// trimBlank drops the blank lines at the start and end of lines.
+func trimBlank(lines []string) []string {
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+const separator = "--- transcript command"
+
+// script turns the commands into a shell script that prints a separator
+// with the exit status after each command, then restores the status for
+// the next command to see. Background jobs are killed at the end. Like on
+// a terminal, ls lists files in columns.
+func script(cmds []command) string {
+	var buf strings.Builder
+	buf.WriteString("exec 2>&1\nls() { command ls -C \"$@\"; }\n")
+	for _, c := range cmds {
+		fmt.Fprintf(&buf, "%s\ns=$?; printf '\\n%s %%d\\n' $s; (exit $s)\n", c.cmd, separator)
+	}
+	buf.WriteString("kill $(jobs -p) 2>/dev/null\nexit 0\n")
+	return buf.String()
+}
+
+// splitOutput splits the output of a script into that of its commands.
+func splitOutput(out string) [][]string {
+	var outputs [][]string
+	var lines []string
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, separator+" ") {
+			outputs = append(outputs, trimBlank(lines))
+			lines = nil
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, " \t"))
+	}
+	return outputs
+}
+
+// matchLine reports whether a line of output matches a line of expected
+// output.
+func matchLine(want, got string) bool {
+	if m := rePat.FindStringSubmatch(want); m != nil {
+		re, err := regexp.Compile("^(?:" + m[1] + ")$")
+		return err == nil && re.MatchString(got)
+	}
+	if !strings.Contains(want, "...") {
+		return want == got
+	}
+	parts := strings.Split(want, "...")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$").MatchString(got)
+}
+
+// matchLines reports whether output matches the expected output, where a
+// "..." line matches any number of lines.
+func matchLines(want, got []string) bool {
+	if len(want) == 0 {
+		return len(got) == 0
+	}
+	if want[0] == "..." {
+		for i := 0; i <= len(got); i++ {
+			if matchLines(want[1:], got[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	return len(got) > 0 && matchLine(want[0], got[0]) && matchLines(want[1:], got[1:])
+}
+
+// diff returns a line diff of the expected and the actual output, with "-"
+// before missing lines and "+" before unexpected ones.
+func diff(want, got []string) string {
+	// lcs[i][j] is the length of the longest common subsequence of want[i:]
+	// and got[j:].
+	lcs := make([][]int, len(want)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(got)+1)
+	}
+	for i := len(want) - 1; i >= 0; i-- {
+		for j := len(got) - 1; j >= 0; j-- {
+			if matchLine(want[i], got[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var buf strings.Builder
+	i, j := 0, 0
+	for i < len(want) || j < len(got) {
+		switch {
+		case i < len(want) && j < len(got) && matchLine(want[i], got[j]):
+			fmt.Fprintf(&buf, "\t  %s\n", got[j])
+			i++
+			j++
+		case j < len(got) && (i == len(want) || lcs[i][j+1] >= lcs[i+1][j]):
+			fmt.Fprintf(&buf, "\t+ %s\n", got[j])
+			j++
+		default:
+			fmt.Fprintf(&buf, "\t- %s\n", want[i])
+			i++
+		}
+	}
+	return buf.String()
+}
+
+// copyExample copies the files of the example in dir to examples/<id> in a
+// temporary directory, leaving out the transcripts and the files of the
+// site, and returns where it put them. A go.mod with the module path and go
+// directive of the repository's goes at the top, so that package paths and
+// the files next to the example's are the same as in the repository.
+func copyExample(dir, tmp string) string {
+	work := filepath.Join(tmp, "examples", filepath.Base(dir))
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(work, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		switch filepath.Ext(path) {
+		case ".sh", ".md":
+			return nil
+		case ".hash":
+			if !strings.Contains(rel, string(filepath.Separator)) {
+				return nil
+			}
+		}
+		if rel == "meta.json" {
+			return nil
+		}
+		dat, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, dat, 0644)
+	})
+	check(err)
+	goMod := "module examples\n"
+	if dat, err := os.ReadFile("go.mod"); err == nil {
+		goMod = strings.Join(goModPat.FindAllString(string(dat), -1), "\n\n") + "\n"
+	}
+	check(os.WriteFile(filepath.Join(tmp, "go.mod"), []byte(goMod), 0644))
+	return work
+}
+
+// goModPat matches the module and go directives of a go.mod.
+var goModPat = regexp.MustCompile(`(?m)^(module|go) \S+$`)
+
+// environ returns the environment the transcripts run in. It has just what
+// go needs, so that what they print doesn't depend on the caller's. Builds
+// still share the caller's caches.
+func environ(home string) []string {
+	env := []string{
+		"HOME=" + home,
+		"PATH=" + os.Getenv("PATH"),
+		"LANG=C.UTF-8",
+		"TERM=dumb",
+		"GOTOOLCHAIN=local",
+		"GOPROXY=off",
+		"GOFLAGS=",
+	}
+	out, err := exec.Command("go", "env", "GOCACHE", "GOMODCACHE", "GOPATH").Output()
+	check(err)
+	for i, name := range []string{"GOCACHE", "GOMODCACHE", "GOPATH"} {
+		env = append(env, name+"="+strings.Split(string(out), "\n")[i])
+	}
+	return env
+}
+
+func mustReadFile(path string) string {
+	dat, err := os.ReadFile(path)
+	check(err)
+	return string(dat)
+}
+
+// unchecked are the examples whose transcripts show the output differently
+// from how it's printed, and why.
+var unchecked = map[string]string{
+	"logging": "its JSON output is wrapped for the site",
+}
+
+// skipReason returns why the transcripts of the example in dir can't be
+// checked, going by unchecked and its imports, or "" if they can.
+func skipReason(dir string) string {
+	if reason, ok := unchecked[filepath.Base(dir)]; ok {
+		return reason
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	check(err)
+	for _, path := range paths {
+		src := mustReadFile(path)
+		if strings.Contains(src, `"net/http"`) && !*network {
+			return "uses the network (run with -network)"
+		}
+		if strings.Contains(src, `"os/signal"`) {
+			return "waits for a ^C"
+		}
+	}
+	return ""
+}
+
+// runTranscript runs the transcript at path in a copy of its example and
+// reports every command whose output doesn't match.
+func runTranscript(path string, timeout time.Duration) []problem {
+	cmds := parseTranscript(mustReadFile(path))
+	if len(cmds) == 0 {
+		return nil
+	}
+	tmp, err := os.MkdirTemp("", "transcript")
+	check(err)
+	defer os.RemoveAll(tmp)
+	work := copyExample(filepath.Dir(path), tmp)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	sh := exec.CommandContext(ctx, "bash", "-c", script(cmds))
+	sh.Dir = work
+	sh.Env = environ(tmp)
+	// Don't wait for a background job that outlives the script.
+	sh.WaitDelay = time.Second
+	var out bytes.Buffer
+	sh.Stdout = &out
+	err = sh.Run()
+	if ctx.Err() != nil {
+		return []problem{{path, 1, fmt.Sprintf("timed out after %v", timeout)}}
+	}
+	if err != nil && !errors.Is(err, exec.ErrWaitDelay) {
+		return []problem{{path, 1, fmt.Sprintf("running transcript: %v", err)}}
+	}
+	outputs := splitOutput(out.String())
+	var problems []problem
+	for i, c := range cmds {
+		if i >= len(outputs) {
+			problems = append(problems, problem{path, c.line, fmt.Sprintf("$ %s: never ran", c.cmd)})
+			continue
+		}
+		if !matchLines(c.output, outputs[i]) {
+			msg := fmt.Sprintf("$ %s: output differs:\n%s", c.cmd, diff(c.output, outputs[i]))
+			problems = append(problems, problem{path, c.line, strings.TrimSuffix(msg, "\n")})
+		}
+	}
+	return problems
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: tools/transcripts [flags] [example ...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	paths, err := filepath.Glob("examples/*/*.sh")
+	check(err)
+	if flag.NArg() > 0 {
+		paths = nil
+		for _, id := range flag.Args() {
+			matches, err := filepath.Glob(filepath.Join("examples", id, "*.sh"))
+			check(err)
+			if len(matches) == 0 {
+				check(fmt.Errorf("no transcripts in examples/%s", id))
+			}
+			paths = append(paths, matches...)
+		}
+	}
+	failed, skipped := 0, 0
+	for _, path := range paths {
+		if reason := skipReason(filepath.Dir(path)); reason != "" {
+			fmt.Printf("skip %s: %s\n", path, reason)
+			skipped++
+			continue
+		}
+		problems := runTranscript(path, *timeout)
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+		if len(problems) > 0 {
+			failed++
+		}
+	}
+	fmt.Printf("%d transcript(s): %d failed, %d skipped\n", len(paths), failed, skipped)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTranscript(t *testing.T) {
+	src := "# Docs.\n$ go run x.go \nout 1\n\n# More docs, then more output.\nout 2\n\n$ ./x\n\n# End.\n"
+	want := []command{
+		{2, "go run x.go", []string{"out 1", "", "out 2"}},
+		{8, "./x", []string{}},
+	}
+	if got := parseTranscript(src); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTranscript = %+v, want %+v", got, want)
+	}
+}
+
+func TestMatchLines(t *testing.T) {
+	tests := []struct {
+		want, got string
+		match     bool
+	}{
+		{"a\nb", "a\nb", true},
+		{"a\nb", "a\nc", false},
+		{"a\nb", "a", false},
+		{"a\n...\nz", "a\nb\nc\nz", true},
+		{"a\n...\nz", "a\nz", true},
+		{"a\n...", "a\nz\n", true},
+		{"a\n...\nz", "a\nb", false},
+		{"took ...s (...)", "took 2.1s (ok)", true},
+		{"took ...s", "took 2.1ms!", false},
+		{"real 0m2.245s # RE: real\t0m2\\.\\d+s", "real\t0m2.031s", true},
+		{"real 0m2.245s # RE: real\t0m2\\.\\d+s", "real\t0m3.031s", false},
+		{"a.b", "a-b", false},
+	}
+	for _, tt := range tests {
+		if got := matchLines(strings.Split(tt.want, "\n"), strings.Split(tt.got, "\n")); got != tt.match {
+			t.Errorf("matchLines(%q, %q) = %v, want %v", tt.want, tt.got, got, tt.match)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	got := diff([]string{"a", "b", "c..."}, []string{"a", "x", "cde"})
+	want := "\t  a\n\t+ x\n\t- b\n\t  cde\n"
+	if got != want {
+		t.Errorf("diff:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRunTranscript(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("data.txt", "b\na\n")
+	// Stdin from a pipe, an expected exit status, and state that carries
+	// over between commands.
+	writeFile("ok.sh", "$ cat data.txt | sort\na\nb\n"+
+		"$ sh -c 'exit 3'\n$ echo $?\n3\n"+
+		"$ touch new\n$ ls new data.txt\ndata.txt  new\n")
+	if problems := runTranscript(filepath.Join(dir, "ok.sh"), time.Minute); len(problems) > 0 {
+		t.Errorf("ok.sh: %v", problems)
+	}
+
+	// The example sits under examples/ next to a go.mod, like in the
+	// repository, and sees none of the caller's environment.
+	t.Setenv("CALLER", "x")
+	writeFile("layout.sh", "$ ls ../..\nexamples  go.mod\n$ basename $PWD\n"+filepath.Base(dir)+"\n$ echo \"[$CALLER]\"\n[]\n")
+	if problems := runTranscript(filepath.Join(dir, "layout.sh"), time.Minute); len(problems) > 0 {
+		t.Errorf("layout.sh: %v", problems)
+	}
+
+	writeFile("drift.sh", "# Docs.\n$ echo one\ntwo\n$ echo three\nthree\n")
+	problems := runTranscript(filepath.Join(dir, "drift.sh"), time.Minute)
+	want := []problem{{filepath.Join(dir, "drift.sh"), 2, "$ echo one: output differs:\n\t+ one\n\t- two"}}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("drift.sh: %q, want %q", problems, want)
+	}
+
+	writeFile("slow.sh", "$ sleep 10\n")
+	problems = runTranscript(filepath.Join(dir, "slow.sh"), 100*time.Millisecond)
+	if len(problems) != 1 || !strings.Contains(problems[0].msg, "timed out") {
+		t.Errorf("slow.sh: %v, want a timeout", problems)
+	}
+}
+
+func TestSkipReason(t *testing.T) {
+	root := t.TempDir()
+	for name, src := range map[string]string{
+		"plain":   "package main\n\nimport \"fmt\"\n",
+		"signals": "package main\n\nimport \"os/signal\"\n",
+		"logging": "package main\n\nimport \"log\"\n",
+	} {
+		dir := filepath.Join(root, name)
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name+".go"), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, want := range map[string]string{
+		"plain":   "",
+		"signals": "waits for a ^C",
+		"logging": unchecked["logging"],
+	} {
+		if got := skipReason(filepath.Join(root, name)); got != want {
+			t.Errorf("skipReason(%s) = %q, want %q", name, got, want)
+		}
+	}
+}
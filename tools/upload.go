@@ -27,6 +27,12 @@ func guessContentType(filename string) string {
 		return "image/png"
 	case ".css":
 		return "text/css"
+	case ".js":
+		return "text/javascript"
+	case ".json":
+		return "application/json"
+	case ".xml":
+		return "application/xml"
 	default:
 		return "text/html"
 	}